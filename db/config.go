@@ -0,0 +1,293 @@
+package db
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	defaultHost = "127.0.0.1"
+	defaultPort = 3306
+)
+
+// Config is connection settings for Open
+type Config struct {
+	User     string
+	Password string
+	// Host is a hostname or an ip address. A path starting with "/" is
+	// treated as a unix socket and Port is ignored.
+	Host     string
+	Port     int
+	Database string
+	// TLS is the value of the driver's tls param
+	// ("true", "false", "skip-verify", "preferred" or a registered config name).
+	TLS          string
+	Params       map[string]string
+	Timeout      time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+
+	// Pool settings. Zero keeps the database/sql default.
+	// A negative MaxIdleConns disables idle connections.
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+
+	// StmtCacheSize enables the prepared statement cache when positive
+	StmtCacheSize int
+}
+
+// fileConfig is the on-disk form of Config. Durations are written as
+// strings like "5s" so that json and yaml files stay human readable.
+type fileConfig struct {
+	User         string            `json:"user" yaml:"user"`
+	Password     string            `json:"password" yaml:"password"`
+	Host         string            `json:"host" yaml:"host"`
+	Port         int               `json:"port" yaml:"port"`
+	Database     string            `json:"database" yaml:"database"`
+	TLS          string            `json:"tls" yaml:"tls"`
+	Params       map[string]string `json:"params" yaml:"params"`
+	Timeout      string            `json:"timeout" yaml:"timeout"`
+	ReadTimeout  string            `json:"read_timeout" yaml:"read_timeout"`
+	WriteTimeout string            `json:"write_timeout" yaml:"write_timeout"`
+
+	MaxOpenConns    int    `json:"max_open_conns" yaml:"max_open_conns"`
+	MaxIdleConns    int    `json:"max_idle_conns" yaml:"max_idle_conns"`
+	ConnMaxLifetime string `json:"conn_max_lifetime" yaml:"conn_max_lifetime"`
+
+	StmtCacheSize int `json:"stmt_cache_size" yaml:"stmt_cache_size"`
+}
+
+// NewConfig returns Config with default host and port
+func NewConfig() *Config {
+	return &Config{
+		Host: defaultHost,
+		Port: defaultPort,
+	}
+}
+
+// ParseDSN is build Config from go-sql-driver/mysql DSN string
+func ParseDSN(dsn string) (*Config, error) {
+	mc, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{
+		User:         mc.User,
+		Password:     mc.Passwd,
+		Database:     mc.DBName,
+		TLS:          mc.TLSConfig,
+		Params:       mc.Params,
+		Timeout:      mc.Timeout,
+		ReadTimeout:  mc.ReadTimeout,
+		WriteTimeout: mc.WriteTimeout,
+	}
+	switch mc.Net {
+	case "unix":
+		c.Host = mc.Addr
+	case "tcp", "tcp6", "":
+		host, port, err := net.SplitHostPort(mc.Addr)
+		if err != nil {
+			return nil, err
+		}
+		c.Host = host
+		if c.Port, err = strconv.Atoi(port); err != nil {
+			return nil, fmt.Errorf("db: invalid port %q in dsn", port)
+		}
+	default:
+		return nil, fmt.Errorf("db: unsupported network %q in dsn", mc.Net)
+	}
+	return c, nil
+}
+
+// ConfigFromEnv is build Config from environment variables.
+// DB_DSN takes precedence over DB_USER, DB_PASSWORD, DB_HOST, DB_PORT,
+// DB_NAME, DB_TLS, DB_PARAMS (url query form, e.g. "charset=utf8mb4"),
+// DB_TIMEOUT, DB_READ_TIMEOUT and DB_WRITE_TIMEOUT.
+// Pool settings are read from DB_MAX_OPEN_CONNS, DB_MAX_IDLE_CONNS,
+// DB_CONN_MAX_LIFETIME and DB_STMT_CACHE_SIZE in both cases.
+func ConfigFromEnv() (*Config, error) {
+	c, err := connConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	ints := []struct {
+		env string
+		n   *int
+	}{
+		{"DB_MAX_OPEN_CONNS", &c.MaxOpenConns},
+		{"DB_MAX_IDLE_CONNS", &c.MaxIdleConns},
+		{"DB_STMT_CACHE_SIZE", &c.StmtCacheSize},
+	}
+	for _, i := range ints {
+		v := os.Getenv(i.env)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("db: invalid %s %q", i.env, v)
+		}
+		*i.n = n
+	}
+	if err := parseDuration("DB_CONN_MAX_LIFETIME", os.Getenv("DB_CONN_MAX_LIFETIME"), &c.ConnMaxLifetime); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func connConfigFromEnv() (*Config, error) {
+	if dsn := os.Getenv("DB_DSN"); dsn != "" {
+		return ParseDSN(dsn)
+	}
+	c := NewConfig()
+	c.User = os.Getenv("DB_USER")
+	c.Password = os.Getenv("DB_PASSWORD")
+	if v := os.Getenv("DB_HOST"); v != "" {
+		c.Host = v
+	}
+	if v := os.Getenv("DB_PORT"); v != "" {
+		port, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("db: invalid DB_PORT %q", v)
+		}
+		c.Port = port
+	}
+	c.Database = os.Getenv("DB_NAME")
+	c.TLS = os.Getenv("DB_TLS")
+	if v := os.Getenv("DB_PARAMS"); v != "" {
+		q, err := url.ParseQuery(v)
+		if err != nil {
+			return nil, fmt.Errorf("db: invalid DB_PARAMS %q: %v", v, err)
+		}
+		c.Params = make(map[string]string, len(q))
+		for k := range q {
+			c.Params[k] = q.Get(k)
+		}
+	}
+	durations := []struct {
+		env string
+		d   *time.Duration
+	}{
+		{"DB_TIMEOUT", &c.Timeout},
+		{"DB_READ_TIMEOUT", &c.ReadTimeout},
+		{"DB_WRITE_TIMEOUT", &c.WriteTimeout},
+	}
+	for _, d := range durations {
+		if err := parseDuration(d.env, os.Getenv(d.env), d.d); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// LoadConfig is build Config from a json or yaml file.
+// The format is decided by the extension (.json, .yaml or .yml).
+func LoadConfig(path string) (*Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var fc fileConfig
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		// unknown keys are an error like in yaml
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&fc)
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(b, &fc)
+	default:
+		return nil, fmt.Errorf("db: unsupported config file %q", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("db: failed to parse %s: %v", path, err)
+	}
+
+	c := NewConfig()
+	c.User = fc.User
+	c.Password = fc.Password
+	if fc.Host != "" {
+		c.Host = fc.Host
+	}
+	if fc.Port != 0 {
+		c.Port = fc.Port
+	}
+	c.Database = fc.Database
+	c.TLS = fc.TLS
+	c.Params = fc.Params
+	c.MaxOpenConns = fc.MaxOpenConns
+	c.MaxIdleConns = fc.MaxIdleConns
+	c.StmtCacheSize = fc.StmtCacheSize
+	durations := []struct {
+		key string
+		v   string
+		d   *time.Duration
+	}{
+		{"timeout", fc.Timeout, &c.Timeout},
+		{"read_timeout", fc.ReadTimeout, &c.ReadTimeout},
+		{"write_timeout", fc.WriteTimeout, &c.WriteTimeout},
+		{"conn_max_lifetime", fc.ConnMaxLifetime, &c.ConnMaxLifetime},
+	}
+	for _, d := range durations {
+		if err := parseDuration(d.key, d.v, d.d); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func parseDuration(name, v string, d *time.Duration) error {
+	if v == "" {
+		return nil
+	}
+	parsed, err := time.ParseDuration(v)
+	if err != nil {
+		return fmt.Errorf("db: invalid %s %q", name, v)
+	}
+	*d = parsed
+	return nil
+}
+
+// DSN is format Config as go-sql-driver/mysql DSN string
+func (c *Config) DSN() string {
+	mc := mysql.NewConfig()
+	mc.User = c.User
+	mc.Passwd = c.Password
+	if strings.HasPrefix(c.Host, "/") {
+		mc.Net = "unix"
+		mc.Addr = c.Host
+	} else {
+		host, port := c.Host, c.Port
+		if host == "" {
+			host = defaultHost
+		}
+		if port == 0 {
+			port = defaultPort
+		}
+		mc.Net = "tcp"
+		mc.Addr = net.JoinHostPort(host, strconv.Itoa(port))
+	}
+	mc.DBName = c.Database
+	mc.TLSConfig = c.TLS
+	mc.Params = c.Params
+	mc.Timeout = c.Timeout
+	mc.ReadTimeout = c.ReadTimeout
+	mc.WriteTimeout = c.WriteTimeout
+	// DATETIME and TIMESTAMP scan into time.Time, in UTC
+	mc.ParseTime = true
+	mc.Loc = time.UTC
+	return mc.FormatDSN()
+}
@@ -0,0 +1,245 @@
+package db
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseDSN(t *testing.T) {
+	tests := []struct {
+		dsn string
+		r   *Config
+		err bool
+	}{
+		{
+			dsn: "user:pass@tcp(10.0.0.1:3307)/app?charset=utf8mb4&readTimeout=3s&timeout=1s",
+			r: &Config{
+				User:        "user",
+				Password:    "pass",
+				Host:        "10.0.0.1",
+				Port:        3307,
+				Database:    "app",
+				Params:      map[string]string{"charset": "utf8mb4"},
+				Timeout:     time.Second,
+				ReadTimeout: 3 * time.Second,
+			},
+			err: false,
+		},
+		{
+			dsn: "user@unix(/tmp/mysql.sock)/app",
+			r: &Config{
+				User:     "user",
+				Host:     "/tmp/mysql.sock",
+				Database: "app",
+			},
+			err: false,
+		},
+		{
+			dsn: "user:pass@tcp(10.0.0.1:3307)/app?timeout=abc",
+			r:   nil,
+			err: true,
+		},
+	}
+	for i, test := range tests {
+		r, err := ParseDSN(test.dsn)
+		if test.err {
+			if err == nil {
+				t.Errorf("%d, expected error, actual nil", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d, expected  %v, actual %v", i, nil, err)
+			continue
+		}
+		if !reflect.DeepEqual(r, test.r) {
+			t.Errorf("%d, expected  %+v, actual %+v", i, test.r, r)
+		}
+	}
+}
+
+func TestConfig_DSN(t *testing.T) {
+	tests := []struct {
+		c *Config
+		r string
+	}{
+		{
+			c: &Config{
+				User:     "user",
+				Password: "pass",
+				Host:     "db.example.com",
+				Port:     3307,
+				Database: "app",
+				TLS:      "skip-verify",
+				Timeout:  5 * time.Second,
+			},
+			r: "user:pass@tcp(db.example.com:3307)/app?parseTime=true&timeout=5s&tls=skip-verify",
+		},
+		{
+			c: &Config{User: "user", Host: "/tmp/mysql.sock", Database: "app"},
+			r: "user@unix(/tmp/mysql.sock)/app?parseTime=true",
+		},
+		{
+			c: &Config{User: "user"},
+			r: "user@tcp(127.0.0.1:3306)/?parseTime=true",
+		},
+	}
+	for i, test := range tests {
+		r := test.c.DSN()
+		if r != test.r {
+			t.Errorf("%d, expected  %v, actual %v", i, test.r, r)
+		}
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	env := map[string]string{
+		"DB_USER":         "user",
+		"DB_PASSWORD":     "pass",
+		"DB_HOST":         "db.example.com",
+		"DB_PORT":         "3307",
+		"DB_NAME":         "app",
+		"DB_TLS":          "true",
+		"DB_PARAMS":       "charset=utf8mb4",
+		"DB_READ_TIMEOUT": "2s",
+
+		"DB_MAX_OPEN_CONNS":    "20",
+		"DB_MAX_IDLE_CONNS":    "-1",
+		"DB_CONN_MAX_LIFETIME": "5m",
+	}
+	for k, v := range env {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	r, err := ConfigFromEnv()
+	if err != nil {
+		t.Fatalf("expected  %v, actual %v", nil, err)
+	}
+	expected := &Config{
+		User:        "user",
+		Password:    "pass",
+		Host:        "db.example.com",
+		Port:        3307,
+		Database:    "app",
+		TLS:         "true",
+		Params:      map[string]string{"charset": "utf8mb4"},
+		ReadTimeout: 2 * time.Second,
+
+		MaxOpenConns:    20,
+		MaxIdleConns:    -1,
+		ConnMaxLifetime: 5 * time.Minute,
+	}
+	if !reflect.DeepEqual(r, expected) {
+		t.Errorf("expected  %+v, actual %+v", expected, r)
+	}
+
+	os.Setenv("DB_PORT", "abc")
+	if _, err := ConfigFromEnv(); err == nil {
+		t.Errorf("expected error for invalid DB_PORT, actual nil")
+	}
+
+	os.Setenv("DB_PORT", "3307")
+	os.Setenv("DB_DSN", "other@tcp(10.0.0.1:3306)/other")
+	defer os.Unsetenv("DB_DSN")
+	r, err = ConfigFromEnv()
+	if err != nil {
+		t.Fatalf("expected  %v, actual %v", nil, err)
+	}
+	if r.User != "other" || r.Database != "other" {
+		t.Errorf("expected DB_DSN to take precedence, actual %+v", r)
+	}
+	if r.MaxOpenConns != 20 {
+		t.Errorf("expected pool settings with DB_DSN, actual %+v", r)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dbconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	expected := &Config{
+		User:     "user",
+		Password: "pass",
+		Host:     "db.example.com",
+		Port:     3306,
+		Database: "app",
+		Params:   map[string]string{"charset": "utf8mb4"},
+		Timeout:  5 * time.Second,
+
+		MaxOpenConns:    10,
+		ConnMaxLifetime: time.Hour,
+	}
+	tests := []struct {
+		name string
+		body string
+		r    *Config
+		err  bool
+	}{
+		{
+			name: "db.json",
+			body: `{"user":"user","password":"pass","host":"db.example.com","database":"app",` +
+				`"params":{"charset":"utf8mb4"},"timeout":"5s","max_open_conns":10,"conn_max_lifetime":"1h"}`,
+			r:   expected,
+			err: false,
+		},
+		{
+			name: "db.yaml",
+			body: "user: user\npassword: pass\nhost: db.example.com\ndatabase: app\n" +
+				"params:\n  charset: utf8mb4\ntimeout: 5s\nmax_open_conns: 10\nconn_max_lifetime: 1h\n",
+			r:   expected,
+			err: false,
+		},
+		{
+			name: "invalid.yml",
+			body: "user: user\ntimeout: five seconds\n",
+			r:    nil,
+			err:  true,
+		},
+		{
+			name: "unknown.json",
+			body: `{"user":"user","max_open_con":10}`,
+			r:    nil,
+			err:  true,
+		},
+		{
+			name: "unknown.yaml",
+			body: "user: user\nmax_open_con: 10\n",
+			r:    nil,
+			err:  true,
+		},
+		{
+			name: "db.toml",
+			body: "user = \"user\"\n",
+			r:    nil,
+			err:  true,
+		},
+	}
+	for i, test := range tests {
+		path := filepath.Join(dir, test.name)
+		if err := ioutil.WriteFile(path, []byte(test.body), 0600); err != nil {
+			t.Fatal(err)
+		}
+		r, err := LoadConfig(path)
+		if test.err {
+			if err == nil {
+				t.Errorf("%d, expected error, actual nil", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d, expected  %v, actual %v", i, nil, err)
+			continue
+		}
+		if !reflect.DeepEqual(r, test.r) {
+			t.Errorf("%d, expected  %+v, actual %+v", i, test.r, r)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/nakamura244/databasesql/interfaces"
+)
+
+// mysqlErrorKinds maps MySQL error numbers to interfaces errors
+var mysqlErrorKinds = map[uint16]error{
+	1062: interfaces.ErrConflict, // ER_DUP_ENTRY
+	1586: interfaces.ErrConflict, // ER_DUP_ENTRY_WITH_KEY_NAME
+
+	1048: interfaces.ErrConstraint, // ER_BAD_NULL_ERROR
+	1216: interfaces.ErrConstraint, // ER_NO_REFERENCED_ROW
+	1217: interfaces.ErrConstraint, // ER_ROW_IS_REFERENCED
+	1364: interfaces.ErrConstraint, // ER_NO_DEFAULT_FOR_FIELD
+	1451: interfaces.ErrConstraint, // ER_ROW_IS_REFERENCED_2
+	1452: interfaces.ErrConstraint, // ER_NO_REFERENCED_ROW_2
+	3819: interfaces.ErrConstraint, // ER_CHECK_CONSTRAINT_VIOLATED
+
+	1205: interfaces.ErrLockTimeout,      // ER_LOCK_WAIT_TIMEOUT
+	3572: interfaces.ErrLockNotAvailable, // ER_LOCK_NOWAIT
+	3024: interfaces.ErrTimeout,          // ER_QUERY_TIMEOUT (max_execution_time)
+}
+
+// postgresErrorKinds maps PostgreSQL SQLSTATE codes to interfaces errors
+var postgresErrorKinds = map[pq.ErrorCode]error{
+	"23505": interfaces.ErrConflict, // unique_violation
+
+	"23502": interfaces.ErrConstraint, // not_null_violation
+	"23503": interfaces.ErrConstraint, // foreign_key_violation
+	"23514": interfaces.ErrConstraint, // check_violation
+
+	"55P03": interfaces.ErrLockNotAvailable, // lock_not_available (NOWAIT, lock_timeout)
+	"57014": interfaces.ErrTimeout,          // query_canceled (statement_timeout)
+}
+
+// translateError wraps driver errors into interfaces.DBError so callers can
+// tell them apart with errors.Is. Other errors are returned as they are.
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var dbErr *interfaces.DBError
+	if errors.As(err, &dbErr) {
+		return err
+	}
+	var kind error
+	var me *mysql.MySQLError
+	var pe *pq.Error
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		kind = interfaces.ErrNotFound
+	case errors.Is(err, context.DeadlineExceeded):
+		kind = interfaces.ErrTimeout
+	case errors.As(err, &me):
+		kind = mysqlErrorKinds[me.Number]
+	case errors.As(err, &pe):
+		kind = postgresErrorKinds[pe.Code]
+	}
+	if kind == nil {
+		return err
+	}
+	return &interfaces.DBError{Kind: kind, Err: err}
+}
@@ -0,0 +1,68 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/nakamura244/databasesql/interfaces"
+)
+
+func TestTranslateError(t *testing.T) {
+	tests := []struct {
+		err  error
+		kind error
+	}{
+		{err: nil, kind: nil},
+		{err: sql.ErrNoRows, kind: interfaces.ErrNotFound},
+		{err: context.DeadlineExceeded, kind: interfaces.ErrTimeout},
+		{err: &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}, kind: interfaces.ErrConflict},
+		{err: fmt.Errorf("exec: %w", &mysql.MySQLError{Number: 1062}), kind: interfaces.ErrConflict},
+		{err: &mysql.MySQLError{Number: 1452, Message: "Cannot add or update a child row"}, kind: interfaces.ErrConstraint},
+		{err: &mysql.MySQLError{Number: 1048, Message: "Column cannot be null"}, kind: interfaces.ErrConstraint},
+		{err: &mysql.MySQLError{Number: 3819, Message: "Check constraint is violated"}, kind: interfaces.ErrConstraint},
+		{err: &mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded"}, kind: interfaces.ErrTimeout},
+		{err: &mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded"}, kind: interfaces.ErrLockTimeout},
+		{err: &mysql.MySQLError{Number: 3572, Message: "Statement aborted because lock(s) could not be acquired immediately and NOWAIT is set"}, kind: interfaces.ErrLockNotAvailable},
+		{err: &mysql.MySQLError{Number: 1064, Message: "syntax error"}, kind: nil},
+		{err: &pq.Error{Code: "23505", Message: "duplicate key value violates unique constraint"}, kind: interfaces.ErrConflict},
+		{err: &pq.Error{Code: "23503", Message: "violates foreign key constraint"}, kind: interfaces.ErrConstraint},
+		{err: &pq.Error{Code: "23502", Message: "violates not-null constraint"}, kind: interfaces.ErrConstraint},
+		{err: &pq.Error{Code: "23514", Message: "violates check constraint"}, kind: interfaces.ErrConstraint},
+		{err: &pq.Error{Code: "57014", Message: "canceling statement due to statement timeout"}, kind: interfaces.ErrTimeout},
+		{err: &pq.Error{Code: "55P03", Message: "could not obtain lock on row"}, kind: interfaces.ErrLockNotAvailable},
+		{err: &pq.Error{Code: "42601", Message: "syntax error"}, kind: nil},
+		{err: errors.New("exec error"), kind: nil},
+	}
+	for i, test := range tests {
+		r := translateError(test.err)
+		if test.kind == nil {
+			if r != test.err {
+				t.Errorf("%d, expected  %v, actual %v", i, test.err, r)
+			}
+			continue
+		}
+		if !errors.Is(r, test.kind) {
+			t.Errorf("%d, expected  %v, actual %v", i, test.kind, r)
+		}
+		if !errors.Is(r, test.err) {
+			t.Errorf("%d, expected %v to be kept, actual %v", i, test.err, r)
+		}
+		// translating twice does not wrap again
+		if translateError(r) != r {
+			t.Errorf("%d, expected translated error to be returned as is", i)
+		}
+	}
+
+	var me *mysql.MySQLError
+	if !errors.As(translateError(&mysql.MySQLError{Number: 1062}), &me) || me.Number != 1062 {
+		t.Errorf("expected errors.As to reach *mysql.MySQLError")
+	}
+	if !IsRetryable(translateError(&mysql.MySQLError{Number: 1205})) {
+		t.Errorf("expected translated lock wait timeout to stay retryable")
+	}
+}
@@ -0,0 +1,156 @@
+package db
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Pinger is implemented by handlers that can check their connection
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// HealthState is snapshot of HealthChecker
+type HealthState struct {
+	Healthy             bool
+	ConsecutiveFailures int
+	LastError           error
+	LastCheck           time.Time
+}
+
+// HealthChecker pings in background and marks the handler unhealthy
+// after Threshold consecutive failures. One successful ping makes it healthy again.
+type HealthChecker struct {
+	pinger    Pinger
+	interval  time.Duration
+	timeout   time.Duration
+	threshold int
+
+	mu    sync.RWMutex
+	state HealthState
+
+	startOnce sync.Once
+	stopOnce  sync.Once
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// defaultHealthInterval is the interval of a HealthChecker given one that is not positive
+const defaultHealthInterval = time.Second
+
+// NewHealthChecker is constructor. The checker starts healthy and does nothing until Start.
+// An interval that is not positive is taken as defaultHealthInterval.
+func NewHealthChecker(p Pinger, interval, timeout time.Duration, threshold int) *HealthChecker {
+	if interval <= 0 {
+		interval = defaultHealthInterval
+	}
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &HealthChecker{
+		pinger:    p,
+		interval:  interval,
+		timeout:   timeout,
+		threshold: threshold,
+		state:     HealthState{Healthy: true},
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Start is run checks every interval until Stop
+func (h *HealthChecker) Start() {
+	h.startOnce.Do(func() {
+		go h.run()
+	})
+}
+
+func (h *HealthChecker) run() {
+	defer close(h.done)
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+	for {
+		h.Check(context.Background())
+		select {
+		case <-h.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop is stop background checks and wait for the running one
+func (h *HealthChecker) Stop() {
+	h.stopOnce.Do(func() {
+		close(h.stop)
+	})
+	// never started: nothing to wait for
+	h.startOnce.Do(func() {
+		close(h.done)
+	})
+	<-h.done
+}
+
+// Check is ping once and update the state
+func (h *HealthChecker) Check(ctx context.Context) error {
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+	}
+	err := h.pinger.Ping(ctx)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.state.LastCheck = time.Now()
+	h.state.LastError = err
+	if err != nil {
+		h.state.ConsecutiveFailures++
+		if h.state.ConsecutiveFailures >= h.threshold {
+			h.state.Healthy = false
+		}
+		return err
+	}
+	h.state.ConsecutiveFailures = 0
+	h.state.Healthy = true
+	return nil
+}
+
+// Healthy is current health
+func (h *HealthChecker) Healthy() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.state.Healthy
+}
+
+// State is current state
+func (h *HealthChecker) State() HealthState {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.state
+}
+
+// StartHealthCheck is start a background HealthChecker for m.
+// A checker started before is stopped. Close stops the checker.
+func (m *Mysql) StartHealthCheck(interval, timeout time.Duration, threshold int) *HealthChecker {
+	h := NewHealthChecker(m, interval, timeout, threshold)
+	m.mu.Lock()
+	old := m.health
+	m.health = h
+	m.mu.Unlock()
+	if old != nil {
+		old.Stop()
+	}
+	h.Start()
+	return h
+}
+
+// Healthy is false once the health checker saw too many failed pings.
+// It is always true when no health checker is running.
+func (m *Mysql) Healthy() bool {
+	m.mu.Lock()
+	h := m.health
+	m.mu.Unlock()
+	return h == nil || h.Healthy()
+}
@@ -0,0 +1,121 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// pingMock fails while failing is true
+type pingMock struct {
+	mu      sync.Mutex
+	failing bool
+	calls   int
+}
+
+func (p *pingMock) Ping(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls++
+	if p.failing {
+		return errors.New("ping error")
+	}
+	return nil
+}
+
+func TestHealthChecker_Check(t *testing.T) {
+	p := &pingMock{failing: true}
+	h := NewHealthChecker(p, time.Hour, time.Second, 3)
+
+	tests := []struct {
+		failing  bool
+		healthy  bool
+		failures int
+	}{
+		{failing: true, healthy: true, failures: 1},
+		{failing: true, healthy: true, failures: 2},
+		{failing: true, healthy: false, failures: 3},
+		{failing: true, healthy: false, failures: 4},
+		{failing: false, healthy: true, failures: 0},
+	}
+	for i, test := range tests {
+		p.failing = test.failing
+		h.Check(context.Background())
+		state := h.State()
+		if state.Healthy != test.healthy {
+			t.Errorf("%d, expected  %v, actual %v", i, test.healthy, state.Healthy)
+		}
+		if state.ConsecutiveFailures != test.failures {
+			t.Errorf("%d, expected  %v, actual %v", i, test.failures, state.ConsecutiveFailures)
+		}
+		if (state.LastError != nil) != test.failing {
+			t.Errorf("%d, unexpected last error %v", i, state.LastError)
+		}
+	}
+}
+
+func TestHealthChecker_StartStop(t *testing.T) {
+	p := &pingMock{failing: true}
+	h := NewHealthChecker(p, time.Millisecond, time.Second, 2)
+	h.Start()
+
+	deadline := time.Now().Add(time.Second)
+	for h.Healthy() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	h.Stop()
+	if h.Healthy() {
+		t.Errorf("expected unhealthy after repeated ping errors")
+	}
+
+	p.mu.Lock()
+	calls := p.calls
+	p.mu.Unlock()
+	time.Sleep(5 * time.Millisecond)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.calls != calls {
+		t.Errorf("expected no ping after Stop, actual %d more", p.calls-calls)
+	}
+
+	// Stop without Start must not block
+	NewHealthChecker(p, time.Millisecond, time.Second, 1).Stop()
+
+	// an interval that is not positive would make the ticker panic in the goroutine
+	for i, interval := range []time.Duration{0, -time.Second} {
+		h := NewHealthChecker(&pingMock{}, interval, time.Second, 1)
+		if h.interval != defaultHealthInterval {
+			t.Errorf("%d, expected  %v, actual %v", i, defaultHealthInterval, h.interval)
+		}
+		h.Start()
+		h.Stop()
+	}
+}
+
+func TestMysql_Healthy(t *testing.T) {
+	m := Mysql{Conn: Mock{errNo: 13}}
+	if !m.Healthy() {
+		t.Errorf("expected healthy without health checker")
+	}
+
+	h := m.StartHealthCheck(time.Millisecond, time.Second, 1)
+	deadline := time.Now().Add(time.Second)
+	for m.Healthy() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if m.Healthy() {
+		t.Errorf("expected unhealthy after ping error")
+	}
+	if h.State().LastError == nil {
+		t.Errorf("expected last error to be recorded")
+	}
+
+	if err := m.Close(); err != nil {
+		t.Errorf("expected  %v, actual %v", nil, err)
+	}
+	if !m.Healthy() {
+		t.Errorf("expected health checker to be stopped by Close")
+	}
+}
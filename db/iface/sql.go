@@ -3,6 +3,7 @@ package iface
 import (
 	"context"
 	"database/sql"
+	"time"
 )
 
 // SQLAPI is interface
@@ -10,22 +11,22 @@ type SQLAPI interface {
 	//Conn(ctx context.Context)  (*sql.Conn, error)
 	//Driver() driver.Driver
 	//Ping() error
-	//PingContext(ctx context.Context) error
+	PingContext(ctx context.Context) error
 	Begin() (*sql.Tx, error)
-	//BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
-	//Prepare(query string) (*sql.Stmt, error)
-	//PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+	Prepare(query string) (*sql.Stmt, error)
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
 	Exec(query string, args ...interface{}) (sql.Result, error)
-	//ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
 	Query(query string, args ...interface{}) (*sql.Rows, error)
 	QueryRow(query string, args ...interface{}) *sql.Row
-	//QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
-	//QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
-	//SetConnMaxLifetime(d time.Duration)
-	//SetMaxIdleConns(n int)
-	//SetMaxOpenConns(n int)
-	//Stats() sql.DBStats
-	//Close() error
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	SetConnMaxLifetime(d time.Duration)
+	SetMaxIdleConns(n int)
+	SetMaxOpenConns(n int)
+	Stats() sql.DBStats
+	Close() error
 }
 
 // TxAPI is interface
@@ -55,9 +56,23 @@ type RowsAPI interface {
 	Scan(dest ...interface{}) error
 	Next() bool
 	Close() error
+	Err() error
+	Columns() ([]string, error)
+	ColumnTypes() ([]*sql.ColumnType, error)
 }
 
 // RowAPI is interface
 type RowAPI interface {
 	Scan(dest ...interface{}) error
 }
+
+// StmtAPI is interface
+type StmtAPI interface {
+	Close() error
+	Exec(args ...interface{}) (sql.Result, error)
+	ExecContext(ctx context.Context, args ...interface{}) (sql.Result, error)
+	Query(args ...interface{}) (*sql.Rows, error)
+	QueryContext(ctx context.Context, args ...interface{}) (*sql.Rows, error)
+	QueryRow(args ...interface{}) *sql.Row
+	QueryRowContext(ctx context.Context, args ...interface{}) *sql.Row
+}
@@ -0,0 +1,192 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/nakamura244/databasesql/interfaces"
+)
+
+// BindNamed rewrites the :name and @name parameters of query to ? placeholders
+// and returns the args for them, taken from arg.
+// arg is a map with string keys, or a struct or a pointer to one whose fields are
+// matched to names like interfaces.ScanStruct matches them to columns.
+// A slice or array value is expanded to one placeholder per element, e.g. IN (:ids),
+// unless it is a driver.Valuer or of bytes; an empty one is an error since IN () is not SQL.
+// Names in quoted strings, quoted identifiers and comments are left as is,
+// as are :: casts and @@ system variables. A query with ? placeholders is an error.
+func BindNamed(query string, arg interface{}) (string, []interface{}, error) {
+	lookup, err := namedLookup(arg)
+	if err != nil {
+		return "", nil, err
+	}
+	var b strings.Builder
+	args := []interface{}{}
+	last := 0
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; c {
+		case '\'', '"', '`':
+			for i++; i < len(query) && query[i] != c; i++ {
+				if query[i] == '\\' && c == '\'' {
+					i++
+				}
+			}
+		case '-':
+			if strings.HasPrefix(query[i:], "--") {
+				for i < len(query) && query[i] != '\n' {
+					i++
+				}
+			}
+		case '/':
+			if strings.HasPrefix(query[i:], "/*") {
+				end := strings.Index(query[i+2:], "*/")
+				if end < 0 {
+					i = len(query)
+					break
+				}
+				i += end + 3
+			}
+		case '?':
+			return "", nil, errors.New("db: BindNamed query has a ? placeholder")
+		case ':', '@':
+			if i+1 < len(query) && query[i+1] == c {
+				// :: cast or @@ system variable
+				i++
+				continue
+			}
+			end := i + 1
+			for end < len(query) && isNameByte(query[end], end == i+1) {
+				end++
+			}
+			if end == i+1 {
+				continue
+			}
+			name := query[i+1 : end]
+			v, ok := lookup(name)
+			if !ok {
+				return "", nil, fmt.Errorf("db: no value for parameter %c%s", c, name)
+			}
+			vs, ok := expandSlice(v)
+			if ok && len(vs) == 0 {
+				return "", nil, fmt.Errorf("db: empty slice for parameter %c%s", c, name)
+			}
+			if !ok {
+				vs = []interface{}{v}
+			}
+			b.WriteString(query[last:i])
+			b.WriteString(strings.Repeat("?, ", len(vs)-1) + "?")
+			args = append(args, vs...)
+			last = end
+			i = end - 1
+		}
+	}
+	b.WriteString(query[last:])
+	return b.String(), args, nil
+}
+
+// isNameByte is whether c can be in a parameter name, first telling whether it would start it
+func isNameByte(c byte, first bool) bool {
+	switch {
+	case c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z':
+		return true
+	case '0' <= c && c <= '9':
+		return !first
+	}
+	return false
+}
+
+// expandSlice is the elements of v when v is a slice or an array that the driver
+// would not take as one value, i.e. neither a driver.Valuer nor of bytes
+func expandSlice(v interface{}) ([]interface{}, bool) {
+	if _, ok := v.(driver.Valuer); ok {
+		return nil, false
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array || rv.Type().Elem().Kind() == reflect.Uint8 {
+		return nil, false
+	}
+	vs := make([]interface{}, rv.Len())
+	for i := range vs {
+		vs[i] = rv.Index(i).Interface()
+	}
+	return vs, true
+}
+
+// namedLookup is the value of a name in arg
+func namedLookup(arg interface{}) (func(string) (interface{}, bool), error) {
+	if m, ok := arg.(map[string]interface{}); ok {
+		return func(name string) (interface{}, bool) {
+			v, ok := m[name]
+			return v, ok
+		}, nil
+	}
+	rv := reflect.ValueOf(arg)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	switch {
+	case rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String:
+		return func(name string) (interface{}, bool) {
+			v := rv.MapIndex(reflect.ValueOf(name).Convert(rv.Type().Key()))
+			if !v.IsValid() {
+				return nil, false
+			}
+			return v.Interface(), true
+		}, nil
+	case rv.Kind() == reflect.Struct:
+		fields := interfaces.StructFields(rv.Type())
+		return func(name string) (interface{}, bool) {
+			index, ok := fields[name]
+			if !ok {
+				return nil, false
+			}
+			v, err := rv.FieldByIndexErr(index)
+			if err != nil {
+				// through a nil embedded pointer
+				return nil, true
+			}
+			return v.Interface(), true
+		}, nil
+	}
+	return nil, fmt.Errorf("db: named parameters need a map or a struct, got %T", arg)
+}
+
+// ExecuteNamed is ExecuteContext with the named parameters of statement bound from arg, see BindNamed
+func (m *Mysql) ExecuteNamed(ctx context.Context, statement string, arg interface{}) (interfaces.Result, error) {
+	statement, args, err := BindNamed(statement, arg)
+	if err != nil {
+		return Result{}, err
+	}
+	return m.ExecuteContext(ctx, statement, args...)
+}
+
+// QueryNamed is QueryContext with the named parameters of statement bound from arg, see BindNamed
+func (m *Mysql) QueryNamed(ctx context.Context, statement string, arg interface{}) (interfaces.Rows, error) {
+	statement, args, err := BindNamed(statement, arg)
+	if err != nil {
+		return new(Rows), err
+	}
+	return m.QueryContext(ctx, statement, args...)
+}
+
+// ExecuteNamed is ExecuteContext in the transaction with named parameters, see BindNamed
+func (tx TX) ExecuteNamed(ctx context.Context, statement string, arg interface{}) (interfaces.Result, error) {
+	statement, args, err := BindNamed(statement, arg)
+	if err != nil {
+		return Result{}, err
+	}
+	return tx.ExecuteContext(ctx, statement, args...)
+}
+
+// QueryNamed is QueryContext in the transaction with named parameters, see BindNamed
+func (tx TX) QueryNamed(ctx context.Context, statement string, arg interface{}) (interfaces.Rows, error) {
+	statement, args, err := BindNamed(statement, arg)
+	if err != nil {
+		return new(Rows), err
+	}
+	return tx.QueryContext(ctx, statement, args...)
+}
@@ -0,0 +1,145 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type namedBase struct {
+	ID uint `db:"id"`
+}
+
+type namedUser struct {
+	namedBase
+	Email     string `db:"email"`
+	Name      sql.NullString
+	CreatedAt time.Time `db:"created_at"`
+	Memo      string    `db:"-"`
+	IDs       []uint    `db:"ids"`
+}
+
+// namedRecorder is Mock that records the statement and args of ExecContext
+type namedRecorder struct {
+	Mock
+	query *string
+	args  *[]interface{}
+}
+
+func (m namedRecorder) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	*m.query, *m.args = query, args
+	return m.Mock.ExecContext(ctx, query, args...)
+}
+
+func TestBindNamed(t *testing.T) {
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	u := namedUser{namedBase: namedBase{ID: 3}, Email: "a@example.com", CreatedAt: created, IDs: []uint{1, 2}}
+	tests := []struct {
+		query    string
+		arg      interface{}
+		expected string
+		args     []interface{}
+	}{
+		{
+			query:    "SELECT * FROM users WHERE id = :id AND email = @email",
+			arg:      map[string]interface{}{"id": 1, "email": "a@example.com"},
+			expected: "SELECT * FROM users WHERE id = ? AND email = ?",
+			args:     []interface{}{1, "a@example.com"},
+		},
+		{
+			query:    "UPDATE users SET email = :email, name = :name, created_at = :created_at WHERE id = :id",
+			arg:      &u,
+			expected: "UPDATE users SET email = ?, name = ?, created_at = ? WHERE id = ?",
+			args:     []interface{}{"a@example.com", sql.NullString{}, created, uint(3)},
+		},
+		{
+			query:    "SELECT * FROM users WHERE id IN (:ids) AND id <> :id",
+			arg:      u,
+			expected: "SELECT * FROM users WHERE id IN (?, ?) AND id <> ?",
+			args:     []interface{}{uint(1), uint(2), uint(3)},
+		},
+		{
+			query:    "SELECT * FROM t WHERE hash = :hash AND n IN (:n)",
+			arg:      map[string][]byte{"hash": []byte("ab"), "n": nil},
+			expected: "SELECT * FROM t WHERE hash = ? AND n IN (?)",
+			args:     []interface{}{[]byte("ab"), []byte(nil)},
+		},
+		{
+			// values the driver takes as they are, not lists
+			query:    "SELECT * FROM t WHERE uuid = :uuid AND tags = :tags AND doc = :doc",
+			arg:      map[string]interface{}{"uuid": [16]byte{1}, "tags": pq.StringArray{"a", "b"}, "doc": json.RawMessage(`{"a":1}`)},
+			expected: "SELECT * FROM t WHERE uuid = ? AND tags = ? AND doc = ?",
+			args:     []interface{}{[16]byte{1}, pq.StringArray{"a", "b"}, json.RawMessage(`{"a":1}`)},
+		},
+		{
+			query:    "SELECT ':id', \"@id\", `:id`, '10:30', 'it''s :id', 'a\\':id' FROM t -- :id\nWHERE /* @id */ id = :id",
+			arg:      map[string]interface{}{"id": 1},
+			expected: "SELECT ':id', \"@id\", `:id`, '10:30', 'it''s :id', 'a\\':id' FROM t -- :id\nWHERE /* @id */ id = ?",
+			args:     []interface{}{1},
+		},
+		{
+			query:    "SELECT :v::text, @@auto_increment_increment, :v1 := 1",
+			arg:      map[string]interface{}{"v": "x", "v1": 2},
+			expected: "SELECT ?::text, @@auto_increment_increment, ? := 1",
+			args:     []interface{}{"x", 2},
+		},
+	}
+	for i, test := range tests {
+		query, args, err := BindNamed(test.query, test.arg)
+		if err != nil {
+			t.Fatalf("%d, expected  %v, actual %v", i, nil, err)
+		}
+		if query != test.expected {
+			t.Errorf("%d, expected  %v, actual %v", i, test.expected, query)
+		}
+		if !reflect.DeepEqual(args, test.args) {
+			t.Errorf("%d, expected  %v, actual %v", i, test.args, args)
+		}
+	}
+}
+
+func TestBindNamed_Error(t *testing.T) {
+	tests := []struct {
+		query string
+		arg   interface{}
+	}{
+		{query: "SELECT * FROM users WHERE id = :id", arg: map[string]interface{}{}},
+		{query: "SELECT * FROM users WHERE id = :memo", arg: namedUser{}},
+		{query: "SELECT * FROM users WHERE id IN (:ids)", arg: map[string]interface{}{"ids": []int{}}},
+		{query: "SELECT * FROM users WHERE id = ? AND email = :email", arg: map[string]interface{}{"email": "a"}},
+		{query: "SELECT * FROM users WHERE id = :id", arg: 1},
+		{query: "SELECT * FROM users WHERE id = :id", arg: nil},
+	}
+	for i, test := range tests {
+		if _, _, err := BindNamed(test.query, test.arg); err == nil {
+			t.Errorf("%d, expected an error, actual %v", i, err)
+		}
+	}
+}
+
+func TestMysql_ExecuteNamed(t *testing.T) {
+	var query string
+	var args []interface{}
+	m := Mysql{Conn: namedRecorder{query: &query, args: &args}}
+	if _, err := m.ExecuteNamed(context.Background(), "DELETE FROM users WHERE id IN (:ids)", map[string]interface{}{"ids": []int{4, 5}}); err != nil {
+		t.Fatalf("expected  %v, actual %v", nil, err)
+	}
+	if expected := "DELETE FROM users WHERE id IN (?, ?)"; query != expected {
+		t.Errorf("expected  %v, actual %v", expected, query)
+	}
+	if expected := []interface{}{4, 5}; !reflect.DeepEqual(args, expected) {
+		t.Errorf("expected  %v, actual %v", expected, args)
+	}
+
+	if _, err := m.ExecuteNamed(context.Background(), "DELETE FROM users WHERE id = :id", map[string]interface{}{}); err == nil {
+		t.Errorf("expected an error, actual %v", err)
+	}
+	if _, err := m.QueryNamed(context.Background(), "SELECT * FROM users WHERE id = :id", struct{}{}); err == nil {
+		t.Errorf("expected an error, actual %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/nakamura244/databasesql/db/iface"
+	"github.com/nakamura244/databasesql/interfaces"
+	"github.com/nakamura244/databasesql/interfaces/query"
+
+	// Postgres Driver
+	_ "github.com/lib/pq"
+)
+
+// Postgres is the handler of a PostgreSQL database. ? placeholders are rewritten
+// to $1, $2, ... and errors are translated to the same interfaces errors as Mysql.
+// Postgres has no LastInsertId: a statement with RETURNING run by Execute has
+// the first column of its first row instead, e.g. INSERT ... RETURNING id.
+// It is an interfaces.Dialecter, so interfaces.Repository writes the Postgres forms
+// of its statements, e.g. ON CONFLICT for Upsert. SQL written for MySQL only,
+// e.g. ON DUPLICATE KEY UPDATE, is sent as it is and fails.
+// A ? outside quotes and comments is always a placeholder, so use the functions
+// of the jsonb ? operators, e.g. jsonb_exists.
+type Postgres struct {
+	*Mysql
+}
+
+// NewPostgres is Postgres on conn
+func NewPostgres(conn iface.SQLAPI) *Postgres {
+	return &Postgres{Mysql: &Mysql{Conn: conn, TxRetry: DefaultRetryPolicy, dialect: query.Postgres}}
+}
+
+// OpenPostgres is open connection pool to the database of dsn,
+// a lib/pq connection string or URL. The pool is configured through p.Conn.
+func OpenPostgres(dsn string) (*Postgres, error) {
+	conn, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	return NewPostgres(conn), nil
+}
+
+// returningResult is the result of an INSERT run with RETURNING
+type returningResult struct {
+	lastID int64
+	rows   int64
+}
+
+func (r returningResult) LastInsertId() (int64, error) {
+	return r.lastID, nil
+}
+
+func (r returningResult) RowsAffected() (int64, error) {
+	return r.rows, nil
+}
+
+// hasReturning is whether statement has a RETURNING clause
+func hasReturning(statement string) bool {
+	return query.HasKeyword(statement, "RETURNING")
+}
+
+// execReturning runs the statement with a RETURNING clause with queryContext.
+// LastInsertId is the first column of the first row, which must be an integer,
+// and RowsAffected the number of rows.
+func execReturning(ctx context.Context, queryContext func(context.Context, string, ...interface{}) (interfaces.Rows, error), statement string, args []interface{}) (interfaces.Result, error) {
+	rows, err := queryContext(ctx, statement, args...)
+	if err != nil {
+		return Result{}, err
+	}
+	res, err := readReturning(rows)
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{Result: res}, nil
+}
+
+// readReturning reads the rows of RETURNING and closes them
+func readReturning(rows interfaces.Rows) (res returningResult, err error) {
+	defer func() {
+		if cerr := rows.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+	cols, err := rows.Columns()
+	if err != nil {
+		return res, err
+	}
+	if len(cols) == 0 {
+		return res, errors.New("db: RETURNING has no columns")
+	}
+	for rows.Next() {
+		if res.rows == 0 {
+			var id sql.NullInt64
+			dest := make([]interface{}, len(cols))
+			dest[0] = &id
+			for i := 1; i < len(dest); i++ {
+				dest[i] = new(interface{})
+			}
+			if err := rows.Scan(dest...); err != nil {
+				return res, err
+			}
+			res.lastID = id.Int64
+		}
+		res.rows++
+	}
+	return res, rows.Err()
+}
@@ -0,0 +1,200 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/nakamura244/databasesql/interfaces/query"
+)
+
+// stmtRecorder is Mock that records the statements sent to the database
+type stmtRecorder struct {
+	Mock
+	queries *[]string
+}
+
+func (m stmtRecorder) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	*m.queries = append(*m.queries, query)
+	return m.Mock.ExecContext(ctx, query, args...)
+}
+
+func (m stmtRecorder) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	*m.queries = append(*m.queries, query)
+	// an error keeps the caller from reading the empty sql.Rows
+	return nil, errors.New("query error")
+}
+
+func (m stmtRecorder) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	*m.queries = append(*m.queries, query)
+	return m.Mock.PrepareContext(ctx, query)
+}
+
+// returningRows is RowsAPI of RETURNING with one row per id
+type returningRows struct {
+	Mock
+	ids  []interface{}
+	next *int
+}
+
+func (r returningRows) Next() bool {
+	*r.next++
+	return *r.next <= len(r.ids)
+}
+
+func (r returningRows) Scan(dest ...interface{}) error {
+	return dest[0].(*sql.NullInt64).Scan(r.ids[*r.next-1])
+}
+
+func TestPostgres_Statements(t *testing.T) {
+	// exec is a statement that must be run by ExecContext, which the recorder lets succeed
+	tests := []struct {
+		run   func(*Postgres) error
+		query string
+		exec  bool
+	}{
+		{
+			run: func(p *Postgres) error {
+				_, err := p.ExecuteContext(context.Background(), "UPDATE users SET email = ? WHERE id = ? AND note <> '?'", "a", 1)
+				return err
+			},
+			query: "UPDATE users SET email = $1 WHERE id = $2 AND note <> '?'",
+		},
+		{
+			run: func(p *Postgres) error {
+				_, err := p.ExecuteContext(context.Background(), "INSERT INTO users ( email ) VALUES (?) ", "a")
+				return err
+			},
+			// without RETURNING nothing is added, whatever the key is
+			query: "INSERT INTO users ( email ) VALUES ($1) ",
+		},
+		{
+			run: func(p *Postgres) error {
+				_, err := p.ExecuteContext(context.Background(), "insert into users (email) values (?) returning user_id;", "a")
+				return err
+			},
+			query: "insert into users (email) values ($1) returning user_id;",
+		},
+		{
+			run: func(p *Postgres) error {
+				_, err := p.QueryContext(context.Background(), "SELECT id FROM users WHERE id IN (?, ?)", 1, 2)
+				return err
+			},
+			query: "SELECT id FROM users WHERE id IN ($1, $2)",
+		},
+		{
+			run: func(p *Postgres) error {
+				_, err := p.PrepareContext(context.Background(), "SELECT id FROM users WHERE id = ?")
+				return err
+			},
+			query: "SELECT id FROM users WHERE id = $1",
+		},
+		{
+			run: func(p *Postgres) error {
+				tx := TX{Tx: p.Conn.(stmtRecorder), dialect: query.Postgres}
+				_, err := tx.ExecuteContext(context.Background(), "INSERT INTO users ( email ) VALUES (?) RETURNING id", "a")
+				return err
+			},
+			query: "INSERT INTO users ( email ) VALUES ($1) RETURNING id",
+		},
+		{
+			run: func(p *Postgres) error {
+				_, err := p.ExecuteContext(context.Background(), "DELETE FROM users WHERE id = ? RETURNING id", 1)
+				return err
+			},
+			query: "DELETE FROM users WHERE id = $1 RETURNING id",
+		},
+		{
+			run: func(p *Postgres) error {
+				_, err := p.ExecuteContext(context.Background(), "UPDATE users SET note = 'returning customer' WHERE id = ?", 1)
+				return err
+			},
+			// RETURNING in a literal is not a clause, the UPDATE is run as Exec
+			query: "UPDATE users SET note = 'returning customer' WHERE id = $1",
+			exec:  true,
+		},
+		{
+			run: func(p *Postgres) error {
+				_, err := p.ExecuteContext(context.Background(), "UPDATE users SET note = ? -- RETURNING id\nWHERE id = ? /* RETURNING */", "a", 1)
+				return err
+			},
+			query: "UPDATE users SET note = $1 -- RETURNING id\nWHERE id = $2 /* RETURNING */",
+			exec:  true,
+		},
+	}
+	for i, test := range tests {
+		var queries []string
+		if err := test.run(NewPostgres(stmtRecorder{queries: &queries})); test.exec && err != nil {
+			t.Errorf("%d, expected  %v, actual %v", i, nil, err)
+		}
+		if len(queries) != 1 || queries[0] != test.query {
+			t.Errorf("%d, expected  %v, actual %v", i, test.query, queries)
+		}
+	}
+
+	if d := NewPostgres(Mock{}).Dialect(); d != query.Postgres {
+		t.Errorf("expected  %v, actual %v", query.Postgres, d)
+	}
+
+	// Mysql sends the statement as it is
+	var queries []string
+	m := Mysql{Conn: stmtRecorder{queries: &queries}}
+	if d := m.Dialect(); d != query.MySQL {
+		t.Errorf("expected  %v, actual %v", query.MySQL, d)
+	}
+	if _, err := m.ExecuteContext(context.Background(), "INSERT INTO users ( email ) VALUES (?)", "a"); err != nil {
+		t.Fatalf("expected  %v, actual %v", nil, err)
+	}
+	if expected := []string{"INSERT INTO users ( email ) VALUES (?)"}; !reflect.DeepEqual(queries, expected) {
+		t.Errorf("expected  %v, actual %v", expected, queries)
+	}
+}
+
+func TestReadReturning(t *testing.T) {
+	tests := []struct {
+		rows     returningRows
+		expected returningResult
+		err      bool
+	}{
+		{rows: returningRows{ids: []interface{}{int64(7)}}, expected: returningResult{lastID: 7, rows: 1}},
+		{rows: returningRows{ids: []interface{}{int64(7), int64(8), int64(9)}}, expected: returningResult{lastID: 7, rows: 3}},
+		{rows: returningRows{ids: []interface{}{}}, expected: returningResult{}},
+		{rows: returningRows{ids: []interface{}{"uuid"}}, err: true},
+		{rows: returningRows{Mock: Mock{errNo: 15}, ids: []interface{}{}}, err: true},
+		{rows: returningRows{Mock: Mock{errNo: 12}, ids: []interface{}{int64(7)}}, err: true},
+	}
+	for i, test := range tests {
+		test.rows.next = new(int)
+		res, err := readReturning(Rows{Rows: test.rows})
+		if test.err {
+			if err == nil {
+				t.Errorf("%d, expected error, actual nil", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d, expected  %v, actual %v", i, nil, err)
+		}
+		if res != test.expected {
+			t.Errorf("%d, expected  %v, actual %v", i, test.expected, res)
+		}
+	}
+}
+
+func TestTX_SavepointPostgres(t *testing.T) {
+	var queries []string
+	tx := TX{Tx: execRecorder{queries: &queries}, dialect: query.Postgres}
+	nested, err := tx.Begin()
+	if err != nil {
+		t.Fatalf("expected  %v, actual %v", nil, err)
+	}
+	if err := nested.Rollback(); err != nil {
+		t.Fatalf("expected  %v, actual %v", nil, err)
+	}
+	expected := []string{`SAVEPOINT "sp_1"`, `ROLLBACK TO SAVEPOINT "sp_1"`}
+	if !reflect.DeepEqual(queries, expected) {
+		t.Errorf("expected  %v, actual %v", expected, queries)
+	}
+}
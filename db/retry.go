@@ -0,0 +1,131 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/nakamura244/databasesql/interfaces"
+)
+
+// MySQL error numbers after which the whole transaction can be run again
+const (
+	errLockWaitTimeout = 1205
+	errDeadlock        = 1213
+)
+
+// PostgreSQL SQLSTATE codes after which the whole transaction can be run again
+const (
+	pqDeadlockDetected     = "40P01"
+	pqSerializationFailure = "40001"
+)
+
+// RetryPolicy is how a transaction is run again after a retryable error.
+// The zero value runs a transaction once.
+type RetryPolicy struct {
+	// MaxAttempts includes the first attempt
+	MaxAttempts int
+	// BaseDelay is the backoff before the second attempt, doubled for each next one
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff. Zero means no cap.
+	MaxDelay time.Duration
+	// Retryable classifies errors. Nil means IsRetryable.
+	Retryable func(error) bool
+}
+
+// DefaultRetryPolicy is 3 attempts with 10ms, 20ms backoff
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   10 * time.Millisecond,
+	MaxDelay:    time.Second,
+}
+
+type retryPolicyKey struct{}
+
+// ContextWithRetryPolicy is ctx with p, which overrides Mysql.TxRetry
+// for WithTx calls made with the returned context
+func ContextWithRetryPolicy(ctx context.Context, p RetryPolicy) context.Context {
+	return context.WithValue(ctx, retryPolicyKey{}, p)
+}
+
+// IsRetryable is true for MySQL deadlock (1213) and lock wait timeout (1205),
+// and for PostgreSQL deadlock (40P01) and serialization failure (40001)
+func IsRetryable(err error) bool {
+	var me *mysql.MySQLError
+	if errors.As(err, &me) {
+		return me.Number == errDeadlock || me.Number == errLockWaitTimeout
+	}
+	var pe *pq.Error
+	if errors.As(err, &pe) {
+		return pe.Code == pqDeadlockDetected || pe.Code == pqSerializationFailure
+	}
+	return false
+}
+
+// WithTxRetry is WithTx with an explicit retry policy. fn is run again in a
+// new transaction after a retryable error, so it must not keep side effects
+// outside the transaction. It returns the number of attempts made.
+func (m *Mysql) WithTxRetry(ctx context.Context, opts *sql.TxOptions, p RetryPolicy, fn func(interfaces.Tx) error) (int, error) {
+	return p.run(ctx, func() error {
+		tx, err := m.BeginTx(ctx, opts)
+		if err != nil {
+			return err
+		}
+		return runTx(tx, fn)
+	})
+}
+
+func (m *Mysql) retryPolicy(ctx context.Context) RetryPolicy {
+	if p, ok := ctx.Value(retryPolicyKey{}).(RetryPolicy); ok {
+		return p
+	}
+	return m.TxRetry
+}
+
+// run is call attempt until it succeeds, fails with a non retryable error,
+// MaxAttempts is reached or ctx is done while backing off
+func (p RetryPolicy) run(ctx context.Context, attempt func() error) (int, error) {
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+	for n := 1; ; n++ {
+		err := attempt()
+		if err == nil || n >= p.MaxAttempts || !retryable(err) {
+			return n, err
+		}
+
+		timer := time.NewTimer(p.backoff(n))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return n, errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff is exponential delay after the n-th attempt with equal jitter:
+// a random duration in [d/2, d)
+func (p RetryPolicy) backoff(n int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < n && d < math.MaxInt64/2; i++ {
+		if p.MaxDelay > 0 && d >= p.MaxDelay {
+			break
+		}
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)))
+}
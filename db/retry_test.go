@@ -0,0 +1,149 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/nakamura244/databasesql/interfaces"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err error
+		r   bool
+	}{
+		{err: &mysql.MySQLError{Number: 1213, Message: "Deadlock found"}, r: true},
+		{err: &mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded"}, r: true},
+		{err: fmt.Errorf("insert: %w", &mysql.MySQLError{Number: 1213}), r: true},
+		{err: &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}, r: false},
+		{err: &pq.Error{Code: "40P01", Message: "deadlock detected"}, r: true},
+		{err: &pq.Error{Code: "40001", Message: "could not serialize access"}, r: true},
+		{err: &pq.Error{Code: "23505", Message: "duplicate key value"}, r: false},
+		{err: errors.New("deadlock"), r: false},
+		{err: nil, r: false},
+	}
+	for i, test := range tests {
+		if r := IsRetryable(test.err); r != test.r {
+			t.Errorf("%d, expected  %v, actual %v", i, test.r, r)
+		}
+	}
+}
+
+func TestRetryPolicy_run(t *testing.T) {
+	deadlock := &mysql.MySQLError{Number: 1213}
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Microsecond}
+	tests := []struct {
+		errs     []error
+		attempts int
+		err      error
+	}{
+		{
+			errs:     []error{nil},
+			attempts: 1,
+			err:      nil,
+		},
+		{
+			errs:     []error{deadlock, deadlock, nil},
+			attempts: 3,
+			err:      nil,
+		},
+		{
+			errs:     []error{deadlock, deadlock, deadlock, nil},
+			attempts: 3,
+			err:      deadlock,
+		},
+		{
+			errs:     []error{errors.New("syntax error"), nil},
+			attempts: 1,
+			err:      errors.New("syntax error"),
+		},
+	}
+	for i, test := range tests {
+		calls := 0
+		attempts, err := policy.run(context.Background(), func() error {
+			err := test.errs[calls]
+			calls++
+			return err
+		})
+		if attempts != test.attempts || calls != test.attempts {
+			t.Errorf("%d, expected %v attempts, actual %v (%v calls)", i, test.attempts, attempts, calls)
+		}
+		if test.err == nil {
+			if err != nil {
+				t.Errorf("%d, expected  %v, actual %v", i, nil, err)
+			}
+		} else if err == nil || err.Error() != test.err.Error() {
+			t.Errorf("%d, expected  %v, actual %v", i, test.err, err)
+		}
+	}
+}
+
+func TestRetryPolicy_runContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	deadlock := &mysql.MySQLError{Number: 1213}
+	policy := RetryPolicy{MaxAttempts: 5, BaseDelay: time.Hour}
+	attempts, err := policy.run(ctx, func() error {
+		return deadlock
+	})
+	if attempts != 1 {
+		t.Errorf("expected  %v, actual %v", 1, attempts)
+	}
+	if !errors.Is(err, context.Canceled) || !errors.Is(err, deadlock) {
+		t.Errorf("expected deadlock and context error, actual %v", err)
+	}
+}
+
+func TestRetryPolicy_backoff(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}
+	tests := []struct {
+		n        int
+		min, max time.Duration
+	}{
+		{n: 1, min: 50 * time.Millisecond, max: 100 * time.Millisecond},
+		{n: 2, min: 100 * time.Millisecond, max: 200 * time.Millisecond},
+		{n: 3, min: 150 * time.Millisecond, max: 300 * time.Millisecond},
+		{n: 70, min: 150 * time.Millisecond, max: 300 * time.Millisecond},
+	}
+	for i, test := range tests {
+		for j := 0; j < 20; j++ {
+			d := p.backoff(test.n)
+			if d < test.min || d >= test.max {
+				t.Errorf("%d, expected in [%v, %v), actual %v", i, test.min, test.max, d)
+			}
+		}
+	}
+}
+
+func TestMysql_WithTxRetry(t *testing.T) {
+	m := Mysql{Conn: Mock{errNo: 1}, TxRetry: RetryPolicy{MaxAttempts: 3}}
+	policy := RetryPolicy{
+		MaxAttempts: 4,
+		Retryable: func(err error) bool {
+			return err.Error() == "begin error"
+		},
+	}
+	attempts, err := m.WithTxRetry(context.Background(), nil, policy, func(interfaces.Tx) error {
+		return nil
+	})
+	if attempts != 4 {
+		t.Errorf("expected  %v, actual %v", 4, attempts)
+	}
+	if err == nil || err.Error() != "begin error" {
+		t.Errorf("expected  %v, actual %v", "begin error", err)
+	}
+
+	// per call policy through context
+	ctx := ContextWithRetryPolicy(context.Background(), policy)
+	if p := m.retryPolicy(ctx); p.MaxAttempts != 4 {
+		t.Errorf("expected  %v, actual %v", 4, p.MaxAttempts)
+	}
+	if p := m.retryPolicy(context.Background()); p.MaxAttempts != 3 {
+		t.Errorf("expected  %v, actual %v", 3, p.MaxAttempts)
+	}
+}
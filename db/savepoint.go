@@ -0,0 +1,96 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sync/atomic"
+
+	"github.com/nakamura244/databasesql/interfaces"
+	"github.com/nakamura244/databasesql/interfaces/query"
+)
+
+var savepointName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,63}$`)
+
+// Savepoint is set a savepoint in transaction
+func (tx TX) Savepoint(name string) error {
+	return tx.SavepointContext(context.Background(), name)
+}
+
+// SavepointContext is Savepoint with context
+func (tx TX) SavepointContext(ctx context.Context, name string) error {
+	return tx.savepointExec(ctx, "SAVEPOINT", name)
+}
+
+// RollbackTo is roll back to a savepoint. The savepoint is kept.
+func (tx TX) RollbackTo(name string) error {
+	return tx.RollbackToContext(context.Background(), name)
+}
+
+// RollbackToContext is RollbackTo with context
+func (tx TX) RollbackToContext(ctx context.Context, name string) error {
+	return tx.savepointExec(ctx, "ROLLBACK TO SAVEPOINT", name)
+}
+
+// Release is remove a savepoint, keeping its changes in transaction
+func (tx TX) Release(name string) error {
+	return tx.ReleaseContext(context.Background(), name)
+}
+
+// ReleaseContext is Release with context
+func (tx TX) ReleaseContext(ctx context.Context, name string) error {
+	return tx.savepointExec(ctx, "RELEASE SAVEPOINT", name)
+}
+
+func (tx TX) savepointExec(ctx context.Context, verb, name string) error {
+	if !savepointName.MatchString(name) {
+		return fmt.Errorf("db: invalid savepoint name %q", name)
+	}
+	quote := "`"
+	if tx.dialect == query.Postgres {
+		quote = `"`
+	}
+	_, err := tx.Tx.ExecContext(ctx, verb+" "+quote+name+quote)
+	return translateError(err)
+}
+
+// Begin is nested transaction. It sets a savepoint; Commit of the returned
+// Tx releases it and Rollback rolls back to it.
+func (tx TX) Begin() (interfaces.Tx, error) {
+	return tx.BeginTx(context.Background(), nil)
+}
+
+// BeginTx is nested transaction with context. opts is ignored, a savepoint
+// runs with the isolation level of the enclosing transaction.
+// ctx is also used by Commit of the returned Tx, and by its Rollback without its
+// cancellation, so that the work of a canceled nested transaction is still undone.
+// Savepoints are numbered in the root transaction, so siblings do not share a name.
+func (tx TX) BeginTx(ctx context.Context, opts *sql.TxOptions) (interfaces.Tx, error) {
+	seq := tx.savepoints
+	if seq == nil {
+		seq = new(int32)
+	}
+	nested := TX{
+		Tx:         tx.Tx,
+		stmts:      tx.stmts,
+		savepoint:  fmt.Sprintf("sp_%d", atomic.AddInt32(seq, 1)),
+		savepoints: seq,
+		ctx:        ctx,
+		dialect:    tx.dialect,
+	}
+	if err := nested.SavepointContext(ctx, nested.savepoint); err != nil {
+		return nil, err
+	}
+	return nested, nil
+}
+
+// nestedTx is nested transaction of the transaction carried by ctx, if any
+func nestedTx(ctx context.Context, opts *sql.TxOptions) (interfaces.Tx, bool, error) {
+	outer, ok := interfaces.TxFromContext(ctx)
+	if !ok {
+		return nil, false, nil
+	}
+	tx, err := outer.BeginTx(ctx, opts)
+	return tx, true, err
+}
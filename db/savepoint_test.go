@@ -0,0 +1,194 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/nakamura244/databasesql/interfaces"
+)
+
+// execRecorder records statements run by ExecContext, failing them when ctx is done
+type execRecorder struct {
+	Mock
+	queries *[]string
+}
+
+func (m execRecorder) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	*m.queries = append(*m.queries, query)
+	return m.Mock.ExecContext(ctx, query, args...)
+}
+
+func (m execRecorder) Commit() error {
+	*m.queries = append(*m.queries, "COMMIT")
+	return m.Mock.Commit()
+}
+
+func (m execRecorder) Rollback() error {
+	*m.queries = append(*m.queries, "ROLLBACK")
+	return m.Mock.Rollback()
+}
+
+func TestTX_Savepoint(t *testing.T) {
+	tests := []struct {
+		f   func(TX, string) error
+		r   string
+		arg string
+		err bool
+	}{
+		{f: TX.Savepoint, arg: "a1", r: "SAVEPOINT `a1`"},
+		{f: TX.RollbackTo, arg: "a1", r: "ROLLBACK TO SAVEPOINT `a1`"},
+		{f: TX.Release, arg: "a1", r: "RELEASE SAVEPOINT `a1`"},
+		{f: TX.Savepoint, arg: "a`; DROP TABLE users; --", err: true},
+		{f: TX.Savepoint, arg: "", err: true},
+	}
+	for i, test := range tests {
+		var queries []string
+		tx := TX{Tx: execRecorder{queries: &queries}}
+		err := test.f(tx, test.arg)
+		if test.err {
+			if err == nil {
+				t.Errorf("%d, expected error, actual nil", i)
+			}
+			if len(queries) != 0 {
+				t.Errorf("%d, expected no statement, actual %v", i, queries)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d, expected  %v, actual %v", i, nil, err)
+		}
+		if len(queries) != 1 || queries[0] != test.r {
+			t.Errorf("%d, expected  %v, actual %v", i, test.r, queries)
+		}
+	}
+
+	tx := TX{Tx: Mock{errNo: 2}}
+	if err := tx.Savepoint("a"); err == nil || err.Error() != "exec error" {
+		t.Errorf("expected  %v, actual %v", "exec error", err)
+	}
+}
+
+func TestTX_Begin(t *testing.T) {
+	var queries []string
+	tx := TX{Tx: execRecorder{queries: &queries}, savepoints: new(int32)}
+
+	nested, err := tx.Begin()
+	if err != nil {
+		t.Fatalf("expected  %v, actual %v", nil, err)
+	}
+	nested2, err := nested.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("expected  %v, actual %v", nil, err)
+	}
+	// a sibling of nested2 gets a savepoint of its own
+	sibling, err := nested.Begin()
+	if err != nil {
+		t.Fatalf("expected  %v, actual %v", nil, err)
+	}
+	if err := nested2.Rollback(); err != nil {
+		t.Fatalf("expected  %v, actual %v", nil, err)
+	}
+	if err := sibling.Commit(); err != nil {
+		t.Fatalf("expected  %v, actual %v", nil, err)
+	}
+	if err := nested.Commit(); err != nil {
+		t.Fatalf("expected  %v, actual %v", nil, err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("expected  %v, actual %v", nil, err)
+	}
+
+	expected := []string{
+		"SAVEPOINT `sp_1`",
+		"SAVEPOINT `sp_2`",
+		"SAVEPOINT `sp_3`",
+		"ROLLBACK TO SAVEPOINT `sp_2`",
+		"RELEASE SAVEPOINT `sp_3`",
+		"RELEASE SAVEPOINT `sp_1`",
+		"COMMIT",
+	}
+	if !reflect.DeepEqual(queries, expected) {
+		t.Errorf("expected  %v, actual %v", expected, queries)
+	}
+}
+
+func TestMysql_WithTxNested(t *testing.T) {
+	var queries []string
+	outer := TX{Tx: execRecorder{queries: &queries}, savepoints: new(int32)}
+	ctx := interfaces.ContextWithTx(context.Background(), outer)
+
+	// the handler would fail to begin a new transaction, so a nested one must be used
+	m := Mysql{Conn: Mock{errNo: 1}}
+	err := m.WithTx(ctx, nil, func(tx interfaces.Tx) error {
+		return nil
+	})
+	if err != nil {
+		t.Errorf("expected  %v, actual %v", nil, err)
+	}
+	fnErr := errors.New("fn error")
+	err = m.WithTx(ctx, nil, func(tx interfaces.Tx) error {
+		return fnErr
+	})
+	if err != fnErr {
+		t.Errorf("expected  %v, actual %v", fnErr, err)
+	}
+
+	expected := []string{
+		"SAVEPOINT `sp_1`",
+		"RELEASE SAVEPOINT `sp_1`",
+		"SAVEPOINT `sp_2`",
+		"ROLLBACK TO SAVEPOINT `sp_2`",
+	}
+	if !reflect.DeepEqual(queries, expected) {
+		t.Errorf("expected  %v, actual %v", expected, queries)
+	}
+
+	if _, err := m.BeginTx(ctx, nil); err != nil {
+		t.Errorf("expected nested BeginTx, actual %v", err)
+	}
+}
+
+func TestTX_BeginTxContext(t *testing.T) {
+	var queries []string
+	tx := TX{Tx: execRecorder{queries: &queries}, savepoints: new(int32)}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := tx.BeginTx(canceled, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected  %v, actual %v", context.Canceled, err)
+	}
+	// a canceled WithTx does not set a savepoint either
+	m := Mysql{Conn: Mock{errNo: 1}}
+	err := m.WithTx(interfaces.ContextWithTx(canceled, tx), nil, func(interfaces.Tx) error {
+		t.Errorf("expected fn not to run")
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected  %v, actual %v", context.Canceled, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	nested, err := tx.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("expected  %v, actual %v", nil, err)
+	}
+	cancel()
+	// the release is canceled, the rollback still undoes the work of nested
+	if err := nested.Commit(); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected  %v, actual %v", context.Canceled, err)
+	}
+	if err := nested.Rollback(); err != nil {
+		t.Errorf("expected  %v, actual %v", nil, err)
+	}
+
+	expected := []string{"SAVEPOINT `sp_3`", "ROLLBACK TO SAVEPOINT `sp_3`"}
+	if !reflect.DeepEqual(queries, expected) {
+		t.Errorf("expected  %v, actual %v", expected, queries)
+	}
+}
@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrClosed is returned for operations started after Shutdown
+var ErrClosed = errors.New("db: handler is shutting down")
+
+// inflight counts operations that still hold a connection: statements being
+// executed, Rows not yet closed, Row not yet scanned and open transactions.
+type inflight struct {
+	mu      sync.Mutex
+	n       int
+	closing bool
+	drained chan struct{}
+}
+
+// acquire registers a new operation. The returned func must be called once
+// the operation has released its connection; calling it again is a no-op.
+func (f *inflight) acquire() (func(), error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closing {
+		return nil, ErrClosed
+	}
+	f.n++
+	var once sync.Once
+	return func() { once.Do(f.release) }, nil
+}
+
+func (f *inflight) release() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.n--
+	if f.closing && f.n == 0 && f.drained != nil {
+		close(f.drained)
+		f.drained = nil
+	}
+}
+
+// drain stops new operations and waits until the running ones finish or ctx is done.
+func (f *inflight) drain(ctx context.Context) error {
+	f.mu.Lock()
+	f.closing = true
+	if f.n == 0 {
+		f.mu.Unlock()
+		return nil
+	}
+	if f.drained == nil {
+		f.drained = make(chan struct{})
+	}
+	drained := f.drained
+	f.mu.Unlock()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown is graceful close. New operations fail with ErrClosed, then
+// Shutdown waits for in-flight ones until ctx is done and closes the pool.
+// If ctx expires first the pool is closed anyway and ctx.Err() is returned.
+func (m *Mysql) Shutdown(ctx context.Context) error {
+	drainErr := m.ops.drain(ctx)
+	if err := m.Close(); err != nil {
+		return err
+	}
+	return drainErr
+}
@@ -0,0 +1,77 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMysql_Shutdown(t *testing.T) {
+	m := Mysql{Conn: Mock{}}
+	done, err := m.ops.acquire()
+	if err != nil {
+		t.Fatalf("expected  %v, actual %v", nil, err)
+	}
+
+	finished := make(chan error, 1)
+	go func() {
+		finished <- m.Shutdown(context.Background())
+	}()
+
+	select {
+	case err := <-finished:
+		t.Fatalf("expected Shutdown to wait for in-flight operation, returned %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// new operations are refused while draining
+	if _, err := m.ExecuteContext(context.Background(), "sql query"); err != ErrClosed {
+		t.Errorf("expected  %v, actual %v", ErrClosed, err)
+	}
+	if err := m.QueryRowContext(context.Background(), "sql").Scan(); err != ErrClosed {
+		t.Errorf("expected  %v, actual %v", ErrClosed, err)
+	}
+
+	done()
+	done() // release is idempotent
+	select {
+	case err := <-finished:
+		if err != nil {
+			t.Errorf("expected  %v, actual %v", nil, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Shutdown did not return after in-flight operation finished")
+	}
+}
+
+func TestMysql_ShutdownDeadline(t *testing.T) {
+	m := Mysql{Conn: Mock{}}
+	if _, err := m.ops.acquire(); err != nil {
+		t.Fatalf("expected  %v, actual %v", nil, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := m.Shutdown(ctx)
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected  %v, actual %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestMysql_ShutdownReleasesRows(t *testing.T) {
+	m := Mysql{Conn: Mock{}}
+	done, err := m.ops.acquire()
+	if err != nil {
+		t.Fatalf("expected  %v, actual %v", nil, err)
+	}
+	rows := Rows{Rows: Mock{}, release: done}
+	if err := rows.Close(); err != nil {
+		t.Fatalf("expected  %v, actual %v", nil, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := m.Shutdown(ctx); err != nil {
+		t.Errorf("expected  %v, actual %v", nil, err)
+	}
+}
@@ -1,11 +1,15 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"log"
+	"sync"
 
 	"github.com/nakamura244/databasesql/db/iface"
 	"github.com/nakamura244/databasesql/interfaces"
+	"github.com/nakamura244/databasesql/interfaces/query"
 
 	// Mysql Driver
 	_ "github.com/go-sql-driver/mysql"
@@ -13,6 +17,15 @@ import (
 
 type Mysql struct {
 	Conn iface.SQLAPI
+	// TxRetry is the retry policy of WithTx. The zero value does not retry.
+	TxRetry RetryPolicy
+
+	ops    inflight
+	stmts  *stmtCache
+	mu     sync.Mutex
+	health *HealthChecker
+	// dialect is query.Postgres for Postgres
+	dialect query.Dialect
 }
 
 // Result is struct
@@ -22,85 +35,418 @@ type Result struct {
 
 // Rows is struct
 type Rows struct {
-	Rows iface.RowsAPI
+	Rows    iface.RowsAPI
+	release func()
 }
 
 // Row is struct for sql.Row
 type Row struct {
-	Row iface.RowAPI
+	Row     iface.RowAPI
+	release func()
 }
 
-// TX is struct for tx
+// TX is struct for tx.
+// A TX made by TX.Begin is a savepoint in the enclosing transaction.
 type TX struct {
-	Tx iface.TxAPI
+	Tx      iface.TxAPI
+	release func()
+	stmts   *stmtCache
+
+	savepoint  string
+	savepoints *int32          // savepoints set in the root transaction, names them uniquely
+	ctx        context.Context // of BeginTx of a nested TX
+	dialect    query.Dialect
+}
+
+// errRow is Row for a query that could not be started
+type errRow struct {
+	err error
+}
+
+func (r errRow) Scan(dest ...interface{}) error {
+	return r.err
+}
+
+// Open is open connection pool with Config
+func Open(cfg *Config) (*Mysql, error) {
+	if cfg == nil {
+		return nil, errors.New("db: config is nil")
+	}
+	conn, err := sql.Open("mysql", cfg.DSN())
+	if err != nil {
+		return nil, err
+	}
+	configurePool(conn, cfg)
+	m := &Mysql{Conn: conn, TxRetry: DefaultRetryPolicy}
+	if cfg.StmtCacheSize > 0 {
+		m.EnableStmtCache(cfg.StmtCacheSize)
+	}
+	return m, nil
 }
 
+func configurePool(conn iface.SQLAPI, cfg *Config) {
+	if cfg.MaxOpenConns != 0 {
+		conn.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns != 0 {
+		conn.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	if cfg.ConnMaxLifetime != 0 {
+		conn.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	}
+}
+
+// Stats is connection pool statistics
+// (open, idle and in-use connections, wait count and duration)
+func (m *Mysql) Stats() sql.DBStats {
+	return m.Conn.Stats()
+}
+
+// NewConn is open connection pool with Config from environment variables.
+//
+// Deprecated: NewConn exits the process on error. Use Open instead.
 func NewConn() *Mysql {
-	conn, err := sql.Open("mysql", "vagrant:vagrant@tcp(192.168.33.10:3306)/geenie2")
+	cfg, err := ConfigFromEnv()
 	if err != nil {
 		log.Fatal(err)
 	}
-	return &Mysql{Conn:conn}
+	conn, err := Open(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return conn
+}
+
+// Ping is check the connection to db
+func (m *Mysql) Ping(ctx context.Context) error {
+	return m.Conn.PingContext(ctx)
+}
+
+// Dialect is the SQL dialect of the database, see interfaces.Dialecter
+func (m *Mysql) Dialect() query.Dialect {
+	return m.dialect
+}
+
+// Close is stop the health checker and close the connection pool
+func (m *Mysql) Close() error {
+	m.mu.Lock()
+	h := m.health
+	m.health = nil
+	m.mu.Unlock()
+	if h != nil {
+		h.Stop()
+	}
+	if m.stmts != nil {
+		m.stmts.close()
+	}
+	return m.Conn.Close()
 }
 
 // Begin is transaction begin
 func (m *Mysql) Begin() (interfaces.Tx, error) {
+	done, err := m.ops.acquire()
+	if err != nil {
+		return nil, err
+	}
 	tx, err := m.Conn.Begin()
+	if err != nil {
+		done()
+		return nil, translateError(err)
+	}
+	sqlTx := new(TX)
+	sqlTx.Tx = tx
+	sqlTx.release = done
+	sqlTx.stmts = m.stmts
+	sqlTx.savepoints = new(int32)
+	sqlTx.dialect = m.dialect
+	return sqlTx, nil
+}
+
+// BeginTx is transaction begin with context and options.
+// When ctx carries a transaction (see interfaces.ContextWithTx) a nested
+// transaction is begun in it instead of a new one.
+func (m *Mysql) BeginTx(ctx context.Context, opts *sql.TxOptions) (interfaces.Tx, error) {
+	if tx, ok, err := nestedTx(ctx, opts); ok {
+		return tx, err
+	}
+	done, err := m.ops.acquire()
 	if err != nil {
 		return nil, err
 	}
+	tx, err := m.Conn.BeginTx(ctx, opts)
+	if err != nil {
+		done()
+		return nil, translateError(err)
+	}
 	sqlTx := new(TX)
 	sqlTx.Tx = tx
+	sqlTx.release = done
+	sqlTx.stmts = m.stmts
+	sqlTx.savepoints = new(int32)
+	sqlTx.dialect = m.dialect
 	return sqlTx, nil
 }
 
 // Execute is execute sql
 func (tx TX) Execute(statement string, args ...interface{}) (interfaces.Result, error) {
+	return tx.ExecuteContext(context.Background(), statement, args...)
+}
+
+// ExecuteContext is execute sql with context.
+// With the statement cache enabled the cached statement is rebound to tx.
+func (tx TX) ExecuteContext(ctx context.Context, statement string, args ...interface{}) (interfaces.Result, error) {
+	if tx.dialect == query.Postgres && hasReturning(statement) {
+		return execReturning(ctx, tx.QueryContext, statement, args)
+	}
+	statement = query.Rebind(tx.dialect, statement)
 	res := Result{}
-	result, err := tx.Tx.Exec(statement, args...)
+	var result sql.Result
+	var err error
+	if stmt, release := tx.cachedStmt(ctx, statement); stmt != nil {
+		result, err = stmt.ExecContext(ctx, args...)
+		release()
+	} else {
+		result, err = tx.Tx.ExecContext(ctx, statement, args...)
+	}
 	if err != nil {
-		return res, err
+		return res, translateError(err)
 	}
 	res.Result = result
 	return res, nil
 }
 
-// Commit is transaction commit
+// Query is query in transaction
+func (tx TX) Query(statement string, args ...interface{}) (interfaces.Rows, error) {
+	return tx.QueryContext(context.Background(), statement, args...)
+}
+
+// QueryContext is query in transaction with context
+func (tx TX) QueryContext(ctx context.Context, statement string, args ...interface{}) (interfaces.Rows, error) {
+	statement = query.Rebind(tx.dialect, statement)
+	var rows *sql.Rows
+	var err error
+	release := func() {}
+	if stmt, r := tx.cachedStmt(ctx, statement); stmt != nil {
+		release = r
+		rows, err = stmt.QueryContext(ctx, args...)
+	} else {
+		rows, err = tx.Tx.QueryContext(ctx, statement, args...)
+	}
+	if err != nil {
+		release()
+		return new(Rows), translateError(err)
+	}
+	row := new(Rows)
+	row.Rows = rows
+	// the statement is in use until the rows are closed
+	row.release = release
+	return row, nil
+}
+
+// QueryRow is query single row in transaction
+func (tx TX) QueryRow(statement string, args ...interface{}) interfaces.Row {
+	return tx.QueryRowContext(context.Background(), statement, args...)
+}
+
+// QueryRowContext is query single row in transaction with context
+func (tx TX) QueryRowContext(ctx context.Context, statement string, args ...interface{}) interfaces.Row {
+	statement = query.Rebind(tx.dialect, statement)
+	row := new(Row)
+	if stmt, release := tx.cachedStmt(ctx, statement); stmt != nil {
+		row.Row = stmt.QueryRowContext(ctx, args...)
+		row.release = release
+	} else {
+		row.Row = tx.Tx.QueryRowContext(ctx, statement, args...)
+	}
+	return row
+}
+
+// Prepare is prepare a statement in transaction.
+// It is closed with the transaction.
+func (tx TX) Prepare(statement string) (interfaces.Stmt, error) {
+	return tx.PrepareContext(context.Background(), statement)
+}
+
+// PrepareContext is prepare a statement in transaction with context.
+// It is closed with the transaction.
+func (tx TX) PrepareContext(ctx context.Context, statement string) (interfaces.Stmt, error) {
+	stmt, err := tx.Tx.PrepareContext(ctx, query.Rebind(tx.dialect, statement))
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &Stmt{Stmt: stmt}, nil
+}
+
+// Stmt is rebind a statement prepared by the handler to tx
+func (tx TX) Stmt(stmt interfaces.Stmt) interfaces.Stmt {
+	return tx.StmtContext(context.Background(), stmt)
+}
+
+// StmtContext is rebind a statement prepared by the handler to tx with context
+func (tx TX) StmtContext(ctx context.Context, stmt interfaces.Stmt) interfaces.Stmt {
+	s, ok := stmt.(*Stmt)
+	if !ok {
+		return errStmt{errForeignStmt}
+	}
+	ps, ok := s.Stmt.(*sql.Stmt)
+	if !ok {
+		return errStmt{errForeignStmt}
+	}
+	return &Stmt{Stmt: tx.Tx.StmtContext(ctx, ps)}
+}
+
+// cachedStmt is the cached statement for query bound to tx and the func that
+// gives it back to the cache, or nil when tx is to run query without a statement.
+// Only statements cached by the handler are used, tx never prepares on the pool.
+func (tx TX) cachedStmt(ctx context.Context, query string) (*sql.Stmt, func()) {
+	if tx.stmts == nil {
+		return nil, nil
+	}
+	s, release, ok := tx.stmts.cached(query)
+	if !ok {
+		return nil, nil
+	}
+	ps, ok := s.(*sql.Stmt)
+	if !ok {
+		release()
+		return nil, nil
+	}
+	return tx.Tx.StmtContext(ctx, ps), release
+}
+
+// cachedStmt is the cached statement for query and the func that gives it back
+// to the cache, or nil when m is to run query without a statement
+func (m *Mysql) cachedStmt(ctx context.Context, query string) (iface.StmtAPI, func()) {
+	if m.stmts == nil {
+		return nil, nil
+	}
+	stmt, release, ok := m.stmts.get(ctx, query)
+	if !ok {
+		return nil, nil
+	}
+	return stmt, release
+}
+
+// releaseAll is the func that calls every func of fs
+func releaseAll(fs ...func()) func() {
+	return func() {
+		for _, f := range fs {
+			f()
+		}
+	}
+}
+
+// Dialect is the SQL dialect of the database, see interfaces.Dialecter
+func (tx TX) Dialect() query.Dialect {
+	return tx.dialect
+}
+
+// Commit is transaction commit, or savepoint release for a nested TX
 func (tx TX) Commit() error {
-	return tx.Tx.Commit()
+	if tx.savepoint != "" {
+		return tx.ReleaseContext(tx.ctx, tx.savepoint)
+	}
+	defer tx.done()
+	return translateError(tx.Tx.Commit())
 }
 
-// Rollback is transaction rollback
+// Rollback is transaction rollback, or rollback to savepoint for a nested TX
 func (tx TX) Rollback() error {
-	return tx.Tx.Rollback()
+	if tx.savepoint != "" {
+		return tx.RollbackToContext(context.WithoutCancel(tx.ctx), tx.savepoint)
+	}
+	defer tx.done()
+	return translateError(tx.Tx.Rollback())
+}
+
+func (tx TX) done() {
+	if tx.release != nil {
+		tx.release()
+	}
 }
 
 // Execute is exe to db
 func (m *Mysql) Execute(statement string, args ...interface{}) (interfaces.Result, error) {
+	return m.ExecuteContext(context.Background(), statement, args...)
+}
+
+// ExecuteContext is exe to db with context
+func (m *Mysql) ExecuteContext(ctx context.Context, statement string, args ...interface{}) (interfaces.Result, error) {
+	if m.dialect == query.Postgres && hasReturning(statement) {
+		return execReturning(ctx, m.QueryContext, statement, args)
+	}
+	statement = query.Rebind(m.dialect, statement)
 	res := Result{}
-	result, err := m.Conn.Exec(statement, args...)
+	done, err := m.ops.acquire()
 	if err != nil {
 		return res, err
 	}
+	defer done()
+	var result sql.Result
+	if stmt, release := m.cachedStmt(ctx, statement); stmt != nil {
+		result, err = stmt.ExecContext(ctx, args...)
+		release()
+	} else {
+		result, err = m.Conn.ExecContext(ctx, statement, args...)
+	}
+	if err != nil {
+		return res, translateError(err)
+	}
 	res.Result = result
 	return res, nil
 }
 
 // Query is query to db
 func (m *Mysql) Query(statement string, args ...interface{}) (interfaces.Rows, error) {
-	rows, err := m.Conn.Query(statement, args...)
+	return m.QueryContext(context.Background(), statement, args...)
+}
+
+// QueryContext is query to db with context
+func (m *Mysql) QueryContext(ctx context.Context, statement string, args ...interface{}) (interfaces.Rows, error) {
+	statement = query.Rebind(m.dialect, statement)
+	done, err := m.ops.acquire()
 	if err != nil {
 		return new(Rows), err
 	}
+	var rows *sql.Rows
+	if stmt, release := m.cachedStmt(ctx, statement); stmt != nil {
+		// the statement is in use until the rows are closed
+		done = releaseAll(release, done)
+		rows, err = stmt.QueryContext(ctx, args...)
+	} else {
+		rows, err = m.Conn.QueryContext(ctx, statement, args...)
+	}
+	if err != nil {
+		done()
+		return new(Rows), translateError(err)
+	}
 	row := new(Rows)
 	row.Rows = rows
+	row.release = done
 	return row, nil
 }
 
 func (m *Mysql) QueryRow(statement string, args ...interface{}) interfaces.Row {
-	r := m.Conn.QueryRow(statement, args...)
+	return m.QueryRowContext(context.Background(), statement, args...)
+}
+
+// QueryRowContext is query single row to db with context
+func (m *Mysql) QueryRowContext(ctx context.Context, statement string, args ...interface{}) interfaces.Row {
+	statement = query.Rebind(m.dialect, statement)
+	done, err := m.ops.acquire()
+	if err != nil {
+		return errRow{err}
+	}
+	var r *sql.Row
+	if stmt, release := m.cachedStmt(ctx, statement); stmt != nil {
+		done = releaseAll(release, done)
+		r = stmt.QueryRowContext(ctx, args...)
+	} else {
+		r = m.Conn.QueryRowContext(ctx, statement, args...)
+	}
 	row := new(Row)
 	row.Row = r
+	row.release = done
 	return row
 }
 
@@ -116,12 +462,15 @@ func (r Result) RowsAffected() (int64, error) {
 
 // Scan is mapping for SQLRows
 func (r Rows) Scan(dest ...interface{}) error {
-	return r.Rows.Scan(dest...)
+	return translateError(r.Rows.Scan(dest...))
 }
 
 // Scan is mapping for SQLRow
 func (r Row) Scan(dest ...interface{}) error {
-	return r.Row.Scan(dest...)
+	if r.release != nil {
+		defer r.release()
+	}
+	return translateError(r.Row.Scan(dest...))
 }
 
 // Next is next row
@@ -131,5 +480,25 @@ func (r Rows) Next() bool {
 
 // Close is close rows
 func (r Rows) Close() error {
+	if r.release != nil {
+		defer r.release()
+	}
 	return r.Rows.Close()
 }
+
+// Err is error encountered during iteration
+func (r Rows) Err() error {
+	return translateError(r.Rows.Err())
+}
+
+// Columns is column names
+func (r Rows) Columns() ([]string, error) {
+	cols, err := r.Rows.Columns()
+	return cols, translateError(err)
+}
+
+// ColumnTypes is column information such as type, length and nullable
+func (r Rows) ColumnTypes() ([]*sql.ColumnType, error) {
+	types, err := r.Rows.ColumnTypes()
+	return types, translateError(err)
+}
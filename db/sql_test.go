@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"database/sql/driver"
 	"errors"
@@ -8,6 +9,7 @@ import (
 	"reflect"
 	"sync"
 	"testing"
+	"time"
 )
 
 type Mock struct {
@@ -29,6 +31,9 @@ type Mock struct {
 	// 10 -> scan error
 	// 11 -> next error
 	// 12 -> close error
+	// 13 -> ping error
+	// 14 -> prepare error
+	// 15 -> rows err error
 	errNo int
 	err   error
 }
@@ -57,6 +62,13 @@ func (m Mock) Begin() (*sql.Tx, error) {
 	return &sql.Tx{}, nil
 }
 
+func (m Mock) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	if m.errNo == 1 {
+		return &sql.Tx{}, errors.New("begin error")
+	}
+	return &sql.Tx{}, nil
+}
+
 func (m Mock) Exec(query string, args ...interface{}) (sql.Result, error) {
 	if m.errNo == 2 {
 		return driverResult{}, errors.New("exec error")
@@ -64,6 +76,13 @@ func (m Mock) Exec(query string, args ...interface{}) (sql.Result, error) {
 	return driverResult{}, nil
 }
 
+func (m Mock) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if m.errNo == 2 {
+		return driverResult{}, errors.New("exec error")
+	}
+	return driverResult{}, nil
+}
+
 func (m Mock) Commit() error {
 	if m.errNo == 3 {
 		return errors.New("commit error")
@@ -85,6 +104,13 @@ func (m Mock) Query(query string, args ...interface{}) (*sql.Rows, error) {
 	return &sql.Rows{}, nil
 }
 
+func (m Mock) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if m.errNo == 6 {
+		return &sql.Rows{}, errors.New("query error")
+	}
+	return &sql.Rows{}, nil
+}
+
 func (m Mock) QueryRow(query string, args ...interface{}) *sql.Row {
 	if m.errNo == 7 {
 		// &sql.Row{err:} にerrorsをセットしたいができない
@@ -93,6 +119,48 @@ func (m Mock) QueryRow(query string, args ...interface{}) *sql.Row {
 	return &sql.Row{}
 }
 
+func (m Mock) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return &sql.Row{}
+}
+
+func (m Mock) Prepare(query string) (*sql.Stmt, error) {
+	if m.errNo == 14 {
+		return nil, errors.New("prepare error")
+	}
+	return &sql.Stmt{}, nil
+}
+
+func (m Mock) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	if m.errNo == 14 {
+		return nil, errors.New("prepare error")
+	}
+	return &sql.Stmt{}, nil
+}
+
+func (m Mock) PingContext(ctx context.Context) error {
+	if m.errNo == 13 {
+		return errors.New("ping error")
+	}
+	return nil
+}
+
+func (m Mock) SetMaxOpenConns(n int) {}
+
+func (m Mock) SetMaxIdleConns(n int) {}
+
+func (m Mock) SetConnMaxLifetime(d time.Duration) {}
+
+func (m Mock) Stats() sql.DBStats {
+	return sql.DBStats{
+		MaxOpenConnections: 10,
+		OpenConnections:    3,
+		InUse:              2,
+		Idle:               1,
+		WaitCount:          4,
+		WaitDuration:       time.Second,
+	}
+}
+
 func (m Mock) LastInsertId() (int64, error) {
 	if m.errNo == 8 {
 		return 0, errors.New("last insert id error")
@@ -128,6 +196,27 @@ func (m Mock) Close() error {
 	return nil
 }
 
+func (m Mock) Err() error {
+	if m.errNo == 15 {
+		return errors.New("rows err error")
+	}
+	return nil
+}
+
+func (m Mock) Columns() ([]string, error) {
+	if m.errNo == 15 {
+		return nil, errors.New("rows err error")
+	}
+	return []string{"id", "email"}, nil
+}
+
+func (m Mock) ColumnTypes() ([]*sql.ColumnType, error) {
+	if m.errNo == 15 {
+		return nil, errors.New("rows err error")
+	}
+	return []*sql.ColumnType{}, nil
+}
+
 func TestNewConn(t *testing.T) {
 	conn := NewConn()
 	expected := "*sql.DB"
@@ -136,6 +225,90 @@ func TestNewConn(t *testing.T) {
 	}
 }
 
+func TestOpen(t *testing.T) {
+	conn, err := Open(&Config{User: "user", Host: "127.0.0.1", Port: 3306, Database: "app", MaxOpenConns: 7})
+	if err != nil {
+		t.Fatalf("expected  %v, actual %v", nil, err)
+	}
+	expected := "*sql.DB"
+	if reflect.TypeOf(conn.Conn).String() != expected {
+		t.Errorf("expected  %v, actual %v", expected, reflect.TypeOf(conn.Conn).String())
+	}
+	if conn.Stats().MaxOpenConnections != 7 {
+		t.Errorf("expected  %v, actual %v", 7, conn.Stats().MaxOpenConnections)
+	}
+
+	if _, err := Open(nil); err == nil {
+		t.Errorf("expected error for nil config, actual nil")
+	}
+}
+
+func TestMysql_Ping(t *testing.T) {
+	tests := []struct {
+		i   iface2.SQLAPI
+		err error
+	}{
+		{
+			i:   Mock{},
+			err: nil,
+		},
+		{
+			i:   Mock{errNo: 13},
+			err: errors.New("ping error"),
+		},
+	}
+	for i, test := range tests {
+		m := Mysql{Conn: test.i}
+		err := m.Ping(context.Background())
+		if test.err != nil {
+			if err.Error() != test.err.Error() {
+				t.Errorf("%d, expected  %v, actual %v", i, test.err.Error(), err.Error())
+			}
+		} else {
+			if err != nil {
+				t.Errorf("%d, expected  %v, actual %v", i, nil, err)
+			}
+		}
+	}
+}
+
+func TestMysql_Close(t *testing.T) {
+	tests := []struct {
+		i   iface2.SQLAPI
+		err error
+	}{
+		{
+			i:   Mock{},
+			err: nil,
+		},
+		{
+			i:   Mock{errNo: 12},
+			err: errors.New("close error"),
+		},
+	}
+	for i, test := range tests {
+		m := Mysql{Conn: test.i}
+		err := m.Close()
+		if test.err != nil {
+			if err.Error() != test.err.Error() {
+				t.Errorf("%d, expected  %v, actual %v", i, test.err.Error(), err.Error())
+			}
+		} else {
+			if err != nil {
+				t.Errorf("%d, expected  %v, actual %v", i, nil, err)
+			}
+		}
+	}
+}
+
+func TestMysql_Stats(t *testing.T) {
+	m := Mysql{Conn: Mock{}}
+	r := m.Stats()
+	if r.OpenConnections != 3 || r.InUse != 2 || r.Idle != 1 || r.WaitCount != 4 {
+		t.Errorf("unexpected stats %+v", r)
+	}
+}
+
 func TestMysql_Begin(t *testing.T) {
 	tests := []struct {
 		i   iface2.SQLAPI
@@ -171,6 +344,41 @@ func TestMysql_Begin(t *testing.T) {
 	}
 }
 
+func TestMysql_BeginTx(t *testing.T) {
+	tests := []struct {
+		i   iface2.SQLAPI
+		r   string
+		err error
+	}{
+		{
+			i:   Mock{},
+			r:   "*db.TX",
+			err: nil,
+		},
+		{
+			i:   Mock{errNo: 1},
+			r:   "",
+			err: errors.New("begin error"),
+		},
+	}
+
+	for i, test := range tests {
+		m := Mysql{
+			Conn: test.i,
+		}
+		tx, err := m.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+		if test.err != nil {
+			if err.Error() != test.err.Error() {
+				t.Errorf("%d, expected  %v, actual %v", i, test.err, err)
+			}
+		} else {
+			if reflect.TypeOf(tx).String() != test.r {
+				t.Errorf("%d, expected  %v, actual %v", i, test.r, reflect.TypeOf(tx).String())
+			}
+		}
+	}
+}
+
 func TestTX_ExecuteWithTx(t *testing.T) {
 	tests := []struct {
 		i   iface2.TxAPI
@@ -190,7 +398,7 @@ func TestTX_ExecuteWithTx(t *testing.T) {
 	}
 
 	for i, test := range tests {
-		m := TX{test.i}
+		m := TX{Tx: test.i}
 		var p interface{}
 		res, err := m.Execute("sql query", p)
 		if test.err != nil {
@@ -205,6 +413,114 @@ func TestTX_ExecuteWithTx(t *testing.T) {
 	}
 }
 
+func TestTX_ExecuteContext(t *testing.T) {
+	tests := []struct {
+		i   iface2.TxAPI
+		r   string
+		err error
+	}{
+		{
+			i:   Mock{},
+			r:   "db.Result",
+			err: nil,
+		},
+		{
+			i:   Mock{errNo: 2},
+			r:   "",
+			err: errors.New("exec error"),
+		},
+	}
+
+	for i, test := range tests {
+		m := TX{Tx: test.i}
+		var p interface{}
+		res, err := m.ExecuteContext(context.Background(), "sql query", p)
+		if test.err != nil {
+			if err.Error() != test.err.Error() {
+				t.Errorf("%d, expected  %v, actual %v", i, test.err.Error(), err.Error())
+			}
+		} else {
+			if reflect.TypeOf(res).String() != test.r {
+				t.Errorf("%d, expected  %v, actual %v", i, test.r, reflect.TypeOf(res).String())
+			}
+		}
+	}
+}
+
+func TestTX_QueryContext(t *testing.T) {
+	tests := []struct {
+		i   iface2.TxAPI
+		r   string
+		err error
+	}{
+		{
+			i:   Mock{},
+			r:   "*db.Rows",
+			err: nil,
+		},
+		{
+			i:   Mock{errNo: 6},
+			r:   "*db.Rows",
+			err: errors.New("query error"),
+		},
+	}
+	for i, test := range tests {
+		m := TX{Tx: test.i}
+		var p interface{}
+		res, err := m.QueryContext(context.Background(), "sql", p)
+		if reflect.TypeOf(res).String() != test.r {
+			t.Errorf("%d, expected  %v, actual %v", i, test.r, reflect.TypeOf(res).String())
+		}
+		if test.err != nil {
+			if err.Error() != test.err.Error() {
+				t.Errorf("%d, expected  %v, actual %v", i, test.err.Error(), err.Error())
+			}
+		}
+	}
+}
+
+func TestTX_QueryRowContext(t *testing.T) {
+	m := TX{Tx: Mock{}}
+	var p interface{}
+	res := m.QueryRowContext(context.Background(), "sql", p)
+	expected := "*db.Row"
+	if reflect.TypeOf(res).String() != expected {
+		t.Errorf("expected  %v, actual %v", expected, reflect.TypeOf(res).String())
+	}
+}
+
+func TestTX_PrepareContext(t *testing.T) {
+	tests := []struct {
+		i   iface2.TxAPI
+		r   string
+		err error
+	}{
+		{
+			i:   Mock{},
+			r:   "*db.Stmt",
+			err: nil,
+		},
+		{
+			i:   Mock{errNo: 14},
+			r:   "",
+			err: errors.New("prepare error"),
+		},
+	}
+	for i, test := range tests {
+		m := TX{Tx: test.i}
+		r, err := m.PrepareContext(context.Background(), "sql")
+		if test.err != nil {
+			if err.Error() != test.err.Error() {
+				t.Errorf("%d, expected  %v, actual %v", i, test.err.Error(), err.Error())
+			}
+		} else {
+			if reflect.TypeOf(r).String() != test.r {
+				t.Errorf("%d, expected  %v, actual %v", i, test.r, reflect.TypeOf(r).String())
+			}
+		}
+	}
+}
+
 func TestTX_Commit(t *testing.T) {
 	tests := []struct {
 		i   iface2.TxAPI
@@ -220,7 +536,7 @@ func TestTX_Commit(t *testing.T) {
 		},
 	}
 	for i, test := range tests {
-		m := TX{test.i}
+		m := TX{Tx: test.i}
 		r := m.Commit()
 		if test.err != nil {
 			if r.Error() != test.err.Error() {
@@ -249,7 +565,7 @@ func TestTX_Rollback(t *testing.T) {
 		},
 	}
 	for i, test := range tests {
-		m := TX{test.i}
+		m := TX{Tx: test.i}
 		r := m.Rollback()
 		if test.err != nil {
 			if r.Error() != test.err.Error() {
@@ -281,7 +597,7 @@ func TestMysql_Execute(t *testing.T) {
 		},
 	}
 	for i, test := range tests {
-		m := Mysql{test.i}
+		m := Mysql{Conn: test.i}
 		var p interface{}
 		res, err := m.Execute("sql query", p)
 		if test.err != nil {
@@ -296,6 +612,39 @@ func TestMysql_Execute(t *testing.T) {
 	}
 }
 
+func TestMysql_ExecuteContext(t *testing.T) {
+	tests := []struct {
+		i   iface2.SQLAPI
+		r   string
+		err error
+	}{
+		{
+			i:   Mock{errNo: 0},
+			r:   "db.Result",
+			err: nil,
+		},
+		{
+			i:   Mock{errNo: 2},
+			r:   "",
+			err: errors.New("exec error"),
+		},
+	}
+	for i, test := range tests {
+		m := Mysql{Conn: test.i}
+		var p interface{}
+		res, err := m.ExecuteContext(context.Background(), "sql query", p)
+		if test.err != nil {
+			if err.Error() != test.err.Error() {
+				t.Errorf("%d, expected  %v, actual %v", i, test.err.Error(), err.Error())
+			}
+		} else {
+			if reflect.TypeOf(res).String() != test.r {
+				t.Errorf("%d, expected  %v, actual %v", i, test.r, reflect.TypeOf(res).String())
+			}
+		}
+	}
+}
+
 func TestMysql_Query(t *testing.T) {
 	tests := []struct {
 		i   iface2.SQLAPI
@@ -314,7 +663,7 @@ func TestMysql_Query(t *testing.T) {
 		},
 	}
 	for i, test := range tests {
-		m := Mysql{test.i}
+		m := Mysql{Conn: test.i}
 		var p interface{}
 		res, err := m.Query("sql", p)
 		if reflect.TypeOf(res).String() != test.r {
@@ -329,7 +678,7 @@ func TestMysql_Query(t *testing.T) {
 }
 
 func TestMysql_QueryRow(t *testing.T) {
-	m := Mysql{Mock{}}
+	m := Mysql{Conn: Mock{}}
 	var p interface{}
 	res := m.QueryRow("sql", p)
 	expected := "*db.Row"
@@ -338,6 +687,48 @@ func TestMysql_QueryRow(t *testing.T) {
 	}
 }
 
+func TestMysql_QueryContext(t *testing.T) {
+	tests := []struct {
+		i   iface2.SQLAPI
+		r   string
+		err error
+	}{
+		{
+			i:   Mock{errNo: 0},
+			r:   "*db.Rows",
+			err: nil,
+		},
+		{
+			i:   Mock{errNo: 6},
+			r:   "*db.Rows",
+			err: errors.New("query error"),
+		},
+	}
+	for i, test := range tests {
+		m := Mysql{Conn: test.i}
+		var p interface{}
+		res, err := m.QueryContext(context.Background(), "sql", p)
+		if reflect.TypeOf(res).String() != test.r {
+			t.Errorf("%d, expected  %v, actual %v", i, test.r, reflect.TypeOf(res).String())
+		}
+		if test.err != nil {
+			if err.Error() != test.err.Error() {
+				t.Errorf("%d, expected  %v, actual %v", i, test.err.Error(), err.Error())
+			}
+		}
+	}
+}
+
+func TestMysql_QueryRowContext(t *testing.T) {
+	m := Mysql{Conn: Mock{}}
+	var p interface{}
+	res := m.QueryRowContext(context.Background(), "sql", p)
+	expected := "*db.Row"
+	if reflect.TypeOf(res).String() != expected {
+		t.Errorf("expected  %v, actual %v", expected, reflect.TypeOf(res).String())
+	}
+}
+
 func TestResult_LastInsertId(t *testing.T) {
 	tests := []struct {
 		i   iface2.ResultAPI
@@ -415,7 +806,7 @@ func TestRows_Scan(t *testing.T) {
 		},
 	}
 	for i, test := range tests {
-		m := Rows{test.i}
+		m := Rows{Rows: test.i}
 		err := m.Scan()
 		if test.err != nil {
 			if err.Error() != test.err.Error() {
@@ -444,7 +835,7 @@ func TestRow_Scan(t *testing.T) {
 		},
 	}
 	for i, test := range tests {
-		m := Row{test.i}
+		m := Row{Row: test.i}
 		err := m.Scan()
 		if test.err != nil {
 			if err.Error() != test.err.Error() {
@@ -473,7 +864,7 @@ func TestRows_Next(t *testing.T) {
 		},
 	}
 	for i, test := range tests {
-		m := Rows{test.i}
+		m := Rows{Rows: test.i}
 		r := m.Next()
 		if r != test.r {
 			t.Errorf("%d, expected  %v, actual %v", i, test.r, r)
@@ -497,7 +888,7 @@ func TestRows_Close(t *testing.T) {
 		},
 	}
 	for i, test := range tests {
-		m := Rows{test.i}
+		m := Rows{Rows: test.i}
 		err := m.Close()
 		if test.err != nil {
 			if err.Error() != test.err.Error() {
@@ -509,4 +900,65 @@ func TestRows_Close(t *testing.T) {
 			}
 		}
 	}
-}
\ No newline at end of file
+}
+func TestRows_Err(t *testing.T) {
+	tests := []struct {
+		i   iface2.RowsAPI
+		err error
+	}{
+		{
+			i:   Mock{errNo: 0},
+			err: nil,
+		},
+		{
+			i:   Mock{errNo: 15},
+			err: errors.New("rows err error"),
+		},
+	}
+	for i, test := range tests {
+		m := Rows{Rows: test.i}
+		err := m.Err()
+		if test.err != nil {
+			if err.Error() != test.err.Error() {
+				t.Errorf("%d, expected  %v, actual %v", i, test.err.Error(), err.Error())
+			}
+		} else {
+			if err != nil {
+				t.Errorf("%d, expected  %v, actual %v", i, nil, err)
+			}
+		}
+	}
+}
+
+func TestRows_Columns(t *testing.T) {
+	tests := []struct {
+		i   iface2.RowsAPI
+		r   []string
+		err error
+	}{
+		{
+			i:   Mock{errNo: 0},
+			r:   []string{"id", "email"},
+			err: nil,
+		},
+		{
+			i:   Mock{errNo: 15},
+			r:   nil,
+			err: errors.New("rows err error"),
+		},
+	}
+	for i, test := range tests {
+		m := Rows{Rows: test.i}
+		r, err := m.Columns()
+		if test.err != nil {
+			if err.Error() != test.err.Error() {
+				t.Errorf("%d, expected  %v, actual %v", i, test.err.Error(), err.Error())
+			}
+		} else if !reflect.DeepEqual(r, test.r) {
+			t.Errorf("%d, expected  %v, actual %v", i, test.r, r)
+		}
+		if _, err := m.ColumnTypes(); (err != nil) != (test.err != nil) {
+			t.Errorf("%d, expected  %v, actual %v", i, test.err, err)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/nakamura244/databasesql/db/iface"
+	"github.com/nakamura244/databasesql/interfaces"
+	"github.com/nakamura244/databasesql/interfaces/query"
+)
+
+var errForeignStmt = errors.New("db: statement was not prepared by this handler")
+
+// Stmt is struct for prepared statement
+type Stmt struct {
+	Stmt iface.StmtAPI
+}
+
+// errStmt is Stmt that could not be prepared or rebound
+type errStmt struct {
+	err error
+}
+
+// Prepare is prepare a statement. The caller must Close it.
+func (m *Mysql) Prepare(statement string) (interfaces.Stmt, error) {
+	return m.PrepareContext(context.Background(), statement)
+}
+
+// PrepareContext is prepare a statement with context. The caller must Close it.
+func (m *Mysql) PrepareContext(ctx context.Context, statement string) (interfaces.Stmt, error) {
+	stmt, err := m.Conn.PrepareContext(ctx, query.Rebind(m.dialect, statement))
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &Stmt{Stmt: stmt}, nil
+}
+
+// EnableStmtCache is prepare the statements run through m more than once and keep
+// up to size of them, least recently used first out. A statement that can not be
+// prepared is run without one. A transaction uses the statements cached already
+// and prepares none. Call it before m is used.
+func (m *Mysql) EnableStmtCache(size int) {
+	m.stmts = newStmtCache(size, func(ctx context.Context, query string) (iface.StmtAPI, error) {
+		stmt, err := m.Conn.PrepareContext(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		return stmt, nil
+	})
+}
+
+// Execute is exe prepared statement
+func (s *Stmt) Execute(args ...interface{}) (interfaces.Result, error) {
+	return s.ExecuteContext(context.Background(), args...)
+}
+
+// ExecuteContext is exe prepared statement with context
+func (s *Stmt) ExecuteContext(ctx context.Context, args ...interface{}) (interfaces.Result, error) {
+	res := Result{}
+	result, err := s.Stmt.ExecContext(ctx, args...)
+	if err != nil {
+		return res, translateError(err)
+	}
+	res.Result = result
+	return res, nil
+}
+
+// Query is query with prepared statement
+func (s *Stmt) Query(args ...interface{}) (interfaces.Rows, error) {
+	return s.QueryContext(context.Background(), args...)
+}
+
+// QueryContext is query with prepared statement and context
+func (s *Stmt) QueryContext(ctx context.Context, args ...interface{}) (interfaces.Rows, error) {
+	rows, err := s.Stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return new(Rows), translateError(err)
+	}
+	row := new(Rows)
+	row.Rows = rows
+	return row, nil
+}
+
+// QueryRow is query single row with prepared statement
+func (s *Stmt) QueryRow(args ...interface{}) interfaces.Row {
+	return s.QueryRowContext(context.Background(), args...)
+}
+
+// QueryRowContext is query single row with prepared statement and context
+func (s *Stmt) QueryRowContext(ctx context.Context, args ...interface{}) interfaces.Row {
+	row := new(Row)
+	row.Row = s.Stmt.QueryRowContext(ctx, args...)
+	return row
+}
+
+// Close is close prepared statement
+func (s *Stmt) Close() error {
+	return s.Stmt.Close()
+}
+
+func (s errStmt) Execute(args ...interface{}) (interfaces.Result, error) {
+	return Result{}, s.err
+}
+
+func (s errStmt) ExecuteContext(ctx context.Context, args ...interface{}) (interfaces.Result, error) {
+	return Result{}, s.err
+}
+
+func (s errStmt) Query(args ...interface{}) (interfaces.Rows, error) {
+	return new(Rows), s.err
+}
+
+func (s errStmt) QueryContext(ctx context.Context, args ...interface{}) (interfaces.Rows, error) {
+	return new(Rows), s.err
+}
+
+func (s errStmt) QueryRow(args ...interface{}) interfaces.Row {
+	return errRow{s.err}
+}
+
+func (s errStmt) QueryRowContext(ctx context.Context, args ...interface{}) interfaces.Row {
+	return errRow{s.err}
+}
+
+func (s errStmt) Close() error {
+	return nil
+}
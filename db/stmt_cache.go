@@ -0,0 +1,182 @@
+package db
+
+import (
+	"container/list"
+	"context"
+	"sync"
+
+	"github.com/nakamura244/databasesql/db/iface"
+)
+
+// stmtCache is LRU cache of prepared statements keyed by SQL text.
+// A statement is prepared the second time its text is run, so that one-off
+// statements, e.g. an IN list of a new length, do not push out the hot ones.
+type stmtCache struct {
+	size    int
+	prepare func(ctx context.Context, query string) (iface.StmtAPI, error)
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+	// seen is the texts run once and not cached yet, the oldest at the back
+	seen      *list.List
+	seenItems map[string]*list.Element
+}
+
+type stmtEntry struct {
+	query string
+	stmt  iface.StmtAPI
+	// refs is the callers using stmt. An evicted entry is closed when it drops to 0.
+	refs    int
+	evicted bool
+}
+
+func newStmtCache(size int, prepare func(ctx context.Context, query string) (iface.StmtAPI, error)) *stmtCache {
+	if size < 1 {
+		size = 1
+	}
+	return &stmtCache{
+		size:      size,
+		prepare:   prepare,
+		ll:        list.New(),
+		items:     make(map[string]*list.Element),
+		seen:      list.New(),
+		seenItems: make(map[string]*list.Element),
+	}
+}
+
+// get is the cached statement for query and the func that gives it back, to be
+// called once the caller is done with the statement and what it returned.
+// ok is false when query is run for the first time or can not be prepared;
+// the caller then runs query without a prepared statement.
+func (c *stmtCache) get(ctx context.Context, query string) (stmt iface.StmtAPI, release func(), ok bool) {
+	c.mu.Lock()
+	if e, ok := c.items[query]; ok {
+		c.ll.MoveToFront(e)
+		entry := e.Value.(*stmtEntry)
+		entry.refs++
+		c.mu.Unlock()
+		return entry.stmt, c.releaser(entry), true
+	}
+	if !c.admit(query) {
+		c.mu.Unlock()
+		return nil, nil, false
+	}
+	c.mu.Unlock()
+
+	// prepare without the lock, it is a round trip to the server
+	stmt, err := c.prepare(ctx, query)
+	if err != nil {
+		return nil, nil, false
+	}
+
+	c.mu.Lock()
+	if e, ok := c.items[query]; ok {
+		// prepared concurrently by another caller
+		c.ll.MoveToFront(e)
+		entry := e.Value.(*stmtEntry)
+		entry.refs++
+		c.mu.Unlock()
+		stmt.Close()
+		return entry.stmt, c.releaser(entry), true
+	}
+	entry := &stmtEntry{query: query, stmt: stmt, refs: 1}
+	c.items[query] = c.ll.PushFront(entry)
+	var unused []iface.StmtAPI
+	for c.ll.Len() > c.size {
+		e := c.ll.Back()
+		c.ll.Remove(e)
+		old := e.Value.(*stmtEntry)
+		delete(c.items, old.query)
+		old.evicted = true
+		if old.refs == 0 {
+			unused = append(unused, old.stmt)
+		}
+	}
+	c.mu.Unlock()
+
+	for _, s := range unused {
+		s.Close()
+	}
+	return stmt, c.releaser(entry), true
+}
+
+// cached is get without preparing: the statement for query and the func that gives
+// it back when it is cached already. A transaction holds its connection, so it must
+// not wait on the pool for another one to prepare on.
+func (c *stmtCache) cached(query string) (stmt iface.StmtAPI, release func(), ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[query]
+	if !ok {
+		return nil, nil, false
+	}
+	c.ll.MoveToFront(e)
+	entry := e.Value.(*stmtEntry)
+	entry.refs++
+	return entry.stmt, c.releaser(entry), true
+}
+
+// admit is whether query was seen before, remembering it when it was not.
+// c.mu must be held.
+func (c *stmtCache) admit(query string) bool {
+	if e, ok := c.seenItems[query]; ok {
+		c.seen.Remove(e)
+		delete(c.seenItems, query)
+		return true
+	}
+	c.seenItems[query] = c.seen.PushFront(query)
+	if c.seen.Len() > c.size {
+		e := c.seen.Back()
+		c.seen.Remove(e)
+		delete(c.seenItems, e.Value.(string))
+	}
+	return false
+}
+
+// releaser is the func that gives entry back, closing it when it was evicted
+// and this was its last user. Calling it more than once does nothing.
+func (c *stmtCache) releaser(entry *stmtEntry) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.mu.Lock()
+			entry.refs--
+			unused := entry.evicted && entry.refs == 0
+			c.mu.Unlock()
+			if unused {
+				entry.stmt.Close()
+			}
+		})
+	}
+}
+
+// len is number of cached statements
+func (c *stmtCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// close is forget every cached statement, closing it now or when its last user gives it back
+func (c *stmtCache) close() {
+	c.mu.Lock()
+	entries := c.ll
+	c.ll = list.New()
+	c.items = make(map[string]*list.Element)
+	c.seen = list.New()
+	c.seenItems = make(map[string]*list.Element)
+	var unused []iface.StmtAPI
+	for e := entries.Front(); e != nil; e = e.Next() {
+		entry := e.Value.(*stmtEntry)
+		entry.evicted = true
+		if entry.refs == 0 {
+			unused = append(unused, entry.stmt)
+		}
+	}
+	c.mu.Unlock()
+
+	for _, s := range unused {
+		s.Close()
+	}
+}
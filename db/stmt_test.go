@@ -0,0 +1,400 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	iface2 "github.com/nakamura244/databasesql/db/iface"
+	"github.com/nakamura244/databasesql/interfaces"
+)
+
+type stmtMock struct {
+	query string
+	// 0 -> no error
+	// 1 -> exec error
+	// 2 -> query error
+	// 3 -> close error
+	errNo  int
+	mu     *sync.Mutex
+	closed *int
+	execs  *int
+}
+
+func newStmtMock(query string, errNo int) stmtMock {
+	return stmtMock{query: query, errNo: errNo, mu: &sync.Mutex{}, closed: new(int), execs: new(int)}
+}
+
+func (s stmtMock) Exec(args ...interface{}) (sql.Result, error) {
+	return s.ExecContext(context.Background(), args...)
+}
+
+func (s stmtMock) ExecContext(ctx context.Context, args ...interface{}) (sql.Result, error) {
+	s.mu.Lock()
+	*s.execs++
+	closed := *s.closed > 0
+	s.mu.Unlock()
+	if closed {
+		return driverResult{}, errors.New("sql: statement is closed")
+	}
+	if s.errNo == 1 {
+		return driverResult{}, errors.New("exec error")
+	}
+	return driverResult{}, nil
+}
+
+func (s stmtMock) Query(args ...interface{}) (*sql.Rows, error) {
+	return s.QueryContext(context.Background(), args...)
+}
+
+func (s stmtMock) QueryContext(ctx context.Context, args ...interface{}) (*sql.Rows, error) {
+	if s.errNo == 2 {
+		return &sql.Rows{}, errors.New("query error")
+	}
+	return &sql.Rows{}, nil
+}
+
+func (s stmtMock) QueryRow(args ...interface{}) *sql.Row {
+	return &sql.Row{}
+}
+
+func (s stmtMock) QueryRowContext(ctx context.Context, args ...interface{}) *sql.Row {
+	return &sql.Row{}
+}
+
+func (s stmtMock) Close() error {
+	s.mu.Lock()
+	*s.closed++
+	s.mu.Unlock()
+	if s.errNo == 3 {
+		return errors.New("close error")
+	}
+	return nil
+}
+
+func TestMysql_PrepareContext(t *testing.T) {
+	tests := []struct {
+		i   iface2.SQLAPI
+		r   string
+		err error
+	}{
+		{
+			i:   Mock{},
+			r:   "*db.Stmt",
+			err: nil,
+		},
+		{
+			i:   Mock{errNo: 14},
+			r:   "",
+			err: errors.New("prepare error"),
+		},
+	}
+	for i, test := range tests {
+		m := Mysql{Conn: test.i}
+		r, err := m.PrepareContext(context.Background(), "sql")
+		if test.err != nil {
+			if err.Error() != test.err.Error() {
+				t.Errorf("%d, expected  %v, actual %v", i, test.err.Error(), err.Error())
+			}
+		} else {
+			if reflect.TypeOf(r).String() != test.r {
+				t.Errorf("%d, expected  %v, actual %v", i, test.r, reflect.TypeOf(r).String())
+			}
+		}
+	}
+}
+
+func TestStmt_ExecuteContext(t *testing.T) {
+	tests := []struct {
+		i   iface2.StmtAPI
+		r   string
+		err error
+	}{
+		{
+			i:   newStmtMock("sql", 0),
+			r:   "db.Result",
+			err: nil,
+		},
+		{
+			i:   newStmtMock("sql", 1),
+			r:   "",
+			err: errors.New("exec error"),
+		},
+	}
+	for i, test := range tests {
+		s := Stmt{Stmt: test.i}
+		res, err := s.ExecuteContext(context.Background(), 1)
+		if test.err != nil {
+			if err.Error() != test.err.Error() {
+				t.Errorf("%d, expected  %v, actual %v", i, test.err.Error(), err.Error())
+			}
+		} else {
+			if reflect.TypeOf(res).String() != test.r {
+				t.Errorf("%d, expected  %v, actual %v", i, test.r, reflect.TypeOf(res).String())
+			}
+		}
+	}
+}
+
+func TestStmt_QueryContext(t *testing.T) {
+	tests := []struct {
+		i   iface2.StmtAPI
+		err error
+	}{
+		{
+			i:   newStmtMock("sql", 0),
+			err: nil,
+		},
+		{
+			i:   newStmtMock("sql", 2),
+			err: errors.New("query error"),
+		},
+	}
+	for i, test := range tests {
+		s := Stmt{Stmt: test.i}
+		res, err := s.QueryContext(context.Background(), 1)
+		if reflect.TypeOf(res).String() != "*db.Rows" {
+			t.Errorf("%d, expected  %v, actual %v", i, "*db.Rows", reflect.TypeOf(res).String())
+		}
+		if test.err != nil {
+			if err.Error() != test.err.Error() {
+				t.Errorf("%d, expected  %v, actual %v", i, test.err.Error(), err.Error())
+			}
+		}
+	}
+}
+
+func TestStmt_Close(t *testing.T) {
+	tests := []struct {
+		i   iface2.StmtAPI
+		err error
+	}{
+		{
+			i:   newStmtMock("sql", 0),
+			err: nil,
+		},
+		{
+			i:   newStmtMock("sql", 3),
+			err: errors.New("close error"),
+		},
+	}
+	for i, test := range tests {
+		s := Stmt{Stmt: test.i}
+		err := s.Close()
+		if test.err != nil {
+			if err.Error() != test.err.Error() {
+				t.Errorf("%d, expected  %v, actual %v", i, test.err.Error(), err.Error())
+			}
+		} else {
+			if err != nil {
+				t.Errorf("%d, expected  %v, actual %v", i, nil, err)
+			}
+		}
+	}
+}
+
+func TestTX_StmtContext(t *testing.T) {
+	tx := TX{Tx: Mock{}}
+	r := tx.StmtContext(context.Background(), &Stmt{Stmt: newStmtMock("sql", 0)})
+	if _, err := r.ExecuteContext(context.Background()); err != errForeignStmt {
+		t.Errorf("expected  %v, actual %v", errForeignStmt, err)
+	}
+}
+
+func TestMysql_StmtCache(t *testing.T) {
+	prepared := map[string]stmtMock{}
+	m := Mysql{Conn: Mock{}}
+	m.stmts = newStmtCache(2, func(ctx context.Context, query string) (iface2.StmtAPI, error) {
+		if query == "broken" {
+			return nil, errors.New("prepare error")
+		}
+		s := newStmtMock(query, 0)
+		prepared[query] = s
+		return s, nil
+	})
+
+	// a statement is prepared when it is run the second time
+	for _, q := range []string{"a", "a", "b", "b", "a", "once"} {
+		if _, err := m.ExecuteContext(context.Background(), q); err != nil {
+			t.Fatalf("expected  %v, actual %v", nil, err)
+		}
+	}
+	if len(prepared) != 2 {
+		t.Errorf("expected 2 statements prepared, actual %d", len(prepared))
+	}
+	if *prepared["a"].execs != 2 {
+		t.Errorf("expected cached statement to be reused, actual %d execs", *prepared["a"].execs)
+	}
+
+	// "b" is least recently used and is evicted by "c"
+	for i := 0; i < 2; i++ {
+		if _, err := m.ExecuteContext(context.Background(), "c"); err != nil {
+			t.Fatalf("expected  %v, actual %v", nil, err)
+		}
+	}
+	if *prepared["b"].closed != 1 || *prepared["a"].closed != 0 {
+		t.Errorf("expected b to be evicted, closed a=%d b=%d", *prepared["a"].closed, *prepared["b"].closed)
+	}
+	if m.stmts.len() != 2 {
+		t.Errorf("expected  %v, actual %v", 2, m.stmts.len())
+	}
+
+	// a statement that can not be prepared is run without one
+	for i := 0; i < 2; i++ {
+		if _, err := m.ExecuteContext(context.Background(), "broken"); err != nil {
+			t.Errorf("expected  %v, actual %v", nil, err)
+		}
+	}
+	if _, ok := prepared["broken"]; ok || m.stmts.len() != 2 {
+		t.Errorf("expected broken not to be cached")
+	}
+
+	if err := m.Close(); err != nil {
+		t.Errorf("expected  %v, actual %v", nil, err)
+	}
+	if *prepared["a"].closed != 1 || *prepared["c"].closed != 1 {
+		t.Errorf("expected cached statements to be closed by Close")
+	}
+}
+
+func TestStmtCache_Lease(t *testing.T) {
+	prepared := map[string]stmtMock{}
+	c := newStmtCache(1, func(ctx context.Context, query string) (iface2.StmtAPI, error) {
+		s := newStmtMock(query, 0)
+		prepared[query] = s
+		return s, nil
+	})
+	get := func(q string) func() {
+		c.get(context.Background(), q)
+		_, release, ok := c.get(context.Background(), q)
+		if !ok {
+			t.Fatalf("expected %s to be cached", q)
+		}
+		return release
+	}
+
+	release := get("a")
+	// "a" is evicted by "b" while it is in use
+	get("b")()
+	if *prepared["a"].closed != 0 {
+		t.Errorf("expected a statement in use not to be closed")
+	}
+	release()
+	release()
+	if *prepared["a"].closed != 1 {
+		t.Errorf("expected  %v, actual %v", 1, *prepared["a"].closed)
+	}
+
+	release = get("c")
+	c.close()
+	if *prepared["b"].closed != 1 || *prepared["c"].closed != 0 {
+		t.Errorf("expected close to wait for the statement in use, closed b=%d c=%d", *prepared["b"].closed, *prepared["c"].closed)
+	}
+	release()
+	if *prepared["c"].closed != 1 {
+		t.Errorf("expected  %v, actual %v", 1, *prepared["c"].closed)
+	}
+}
+
+func TestMysql_StmtCacheConcurrent(t *testing.T) {
+	m := Mysql{Conn: Mock{}}
+	m.stmts = newStmtCache(1, func(ctx context.Context, query string) (iface2.StmtAPI, error) {
+		return newStmtMock(query, 0), nil
+	})
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			// two queries alternate in a cache of one, evicting each other
+			queries := []string{"a", "b"}
+			for i := 0; i < 200; i++ {
+				if _, err := m.ExecuteContext(context.Background(), queries[(i+g)%2]); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("expected  %v, actual %v", nil, err)
+	}
+}
+
+// fakeConnector opens connections whose statements do nothing, for a real *sql.DB in tests
+type fakeConnector struct{}
+
+func (fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn{}, nil }
+func (fakeConnector) Driver() driver.Driver                        { return fakeDriver{} }
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+// fakeConn is driver.Conn and the driver.Tx begun on it
+type fakeConn struct{}
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return fakeStmt{}, nil }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Begin() (driver.Tx, error)           { return fakeConn{}, nil }
+func (fakeConn) Commit() error                       { return nil }
+func (fakeConn) Rollback() error                     { return nil }
+
+type fakeStmt struct{}
+
+func (fakeStmt) Close() error  { return nil }
+func (fakeStmt) NumInput() int { return -1 }
+func (fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+func (fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, errors.New("query error")
+}
+
+func TestTX_StmtCacheOneConn(t *testing.T) {
+	conn := sql.OpenDB(fakeConnector{})
+	defer conn.Close()
+	conn.SetMaxOpenConns(1)
+	m := &Mysql{Conn: conn}
+	m.EnableStmtCache(10)
+
+	// the transaction holds the only connection, preparing on the pool would wait for it
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := m.WithTx(ctx, nil, func(tx interfaces.Tx) error {
+		for i := 0; i < 3; i++ {
+			if _, err := tx.ExecuteContext(ctx, "UPDATE users SET name = ?", "a"); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected  %v, actual %v", nil, err)
+	}
+	if m.stmts.len() != 0 {
+		t.Errorf("expected no statement prepared in the transaction, actual %d", m.stmts.len())
+	}
+
+	// a statement cached by the handler is used in the transaction
+	for i := 0; i < 2; i++ {
+		if _, err := m.ExecuteContext(ctx, "UPDATE users SET name = ?", "a"); err != nil {
+			t.Fatalf("expected  %v, actual %v", nil, err)
+		}
+	}
+	err = m.WithTx(ctx, nil, func(tx interfaces.Tx) error {
+		_, err := tx.ExecuteContext(ctx, "UPDATE users SET name = ?", "a")
+		return err
+	})
+	if err != nil || m.stmts.len() != 1 {
+		t.Errorf("expected 1 cached statement, actual %d %v", m.stmts.len(), err)
+	}
+}
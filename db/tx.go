@@ -0,0 +1,48 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/nakamura244/databasesql/interfaces"
+)
+
+// WithTx is run fn in a transaction.
+// It commits when fn returns nil and rolls back when fn returns an error or
+// panics. A rollback error is joined into the returned error; after a panic
+// the transaction is rolled back and the panic goes on.
+// Deadlocks and lock wait timeouts are retried according to m.TxRetry or
+// the policy set by ContextWithRetryPolicy.
+// When ctx carries a transaction (see interfaces.ContextWithTx) fn runs in a
+// savepoint of it and is not retried; retrying is up to the outermost WithTx.
+func (m *Mysql) WithTx(ctx context.Context, opts *sql.TxOptions, fn func(interfaces.Tx) error) error {
+	if tx, ok, err := nestedTx(ctx, opts); ok {
+		if err != nil {
+			return err
+		}
+		return runTx(tx, fn)
+	}
+	_, err := m.WithTxRetry(ctx, opts, m.retryPolicy(ctx), fn)
+	return err
+}
+
+func runTx(tx interfaces.Tx, fn func(interfaces.Tx) error) (err error) {
+	panicked := true
+	defer func() {
+		if panicked {
+			// the panic is more useful to the caller than a rollback error
+			tx.Rollback()
+		}
+	}()
+
+	err = fn(tx)
+	panicked = false
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return errors.Join(err, rbErr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
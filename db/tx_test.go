@@ -0,0 +1,118 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	iface2 "github.com/nakamura244/databasesql/db/iface"
+	"github.com/nakamura244/databasesql/interfaces"
+)
+
+// txMock records how the transaction ended
+type txMock struct {
+	Mock
+	committed  *bool
+	rolledBack *bool
+}
+
+func (m txMock) Commit() error {
+	*m.committed = true
+	return m.Mock.Commit()
+}
+
+func (m txMock) Rollback() error {
+	*m.rolledBack = true
+	return m.Mock.Rollback()
+}
+
+func TestRunTx(t *testing.T) {
+	fnErr := errors.New("fn error")
+	tests := []struct {
+		errNo      int
+		fnErr      error
+		committed  bool
+		rolledBack bool
+		err        string
+	}{
+		{
+			errNo:     0,
+			fnErr:     nil,
+			committed: true,
+			err:       "",
+		},
+		{
+			errNo:      0,
+			fnErr:      fnErr,
+			rolledBack: true,
+			err:        "fn error",
+		},
+		{
+			errNo:     3,
+			fnErr:     nil,
+			committed: true,
+			err:       "commit error",
+		},
+		{
+			errNo:      4,
+			fnErr:      fnErr,
+			rolledBack: true,
+			err:        "fn error\nrollback error",
+		},
+	}
+	for i, test := range tests {
+		var committed, rolledBack bool
+		var tx iface2.TxAPI = txMock{Mock: Mock{errNo: test.errNo}, committed: &committed, rolledBack: &rolledBack}
+		err := runTx(TX{Tx: tx}, func(interfaces.Tx) error {
+			return test.fnErr
+		})
+		if test.err == "" {
+			if err != nil {
+				t.Errorf("%d, expected  %v, actual %v", i, nil, err)
+			}
+		} else {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%d, expected  %v, actual %v", i, test.err, err)
+			}
+		}
+		if test.fnErr != nil && !errors.Is(err, test.fnErr) {
+			t.Errorf("%d, expected error to wrap %v", i, test.fnErr)
+		}
+		if committed != test.committed || rolledBack != test.rolledBack {
+			t.Errorf("%d, expected commit %v rollback %v, actual commit %v rollback %v",
+				i, test.committed, test.rolledBack, committed, rolledBack)
+		}
+	}
+}
+
+func TestRunTx_Panic(t *testing.T) {
+	var committed, rolledBack bool
+	tx := TX{Tx: txMock{Mock: Mock{}, committed: &committed, rolledBack: &rolledBack}}
+	defer func() {
+		p := recover()
+		if p != "boom" {
+			t.Errorf("expected  %v, actual %v", "boom", p)
+		}
+		if committed || !rolledBack {
+			t.Errorf("expected rollback after panic, actual commit %v rollback %v", committed, rolledBack)
+		}
+	}()
+	runTx(tx, func(interfaces.Tx) error {
+		panic("boom")
+	})
+}
+
+func TestMysql_WithTx(t *testing.T) {
+	m := Mysql{Conn: Mock{errNo: 1}}
+	called := false
+	err := m.WithTx(context.Background(), nil, func(interfaces.Tx) error {
+		called = true
+		return nil
+	})
+	if err == nil || err.Error() != "begin error" {
+		t.Errorf("expected  %v, actual %v", "begin error", err)
+	}
+	if called {
+		t.Errorf("expected fn not to be called when Begin fails")
+	}
+}
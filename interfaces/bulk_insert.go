@@ -0,0 +1,163 @@
+package interfaces
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/nakamura244/databasesql/interfaces/query"
+)
+
+const (
+	// maxPlaceholders is the most placeholders a MySQL prepared statement takes
+	maxPlaceholders = 65535
+	// maxAllowedPacket is the default max_allowed_packet of MySQL 5.7,
+	// kept as the limit so that bulk inserts also fit on older servers
+	maxAllowedPacket = 4 << 20
+)
+
+// InsertMany inserts vs with multi-row INSERT statements in one transaction and
+// returns their primary keys in the order of vs. The statements are split so that
+// each stays under max_allowed_packet and the placeholder limit.
+// The keys are computed from LastInsertId, the key of the first row of a statement,
+// and @@auto_increment_increment. InnoDB gives the rows of such a statement
+// consecutive keys in every innodb_autoinc_lock_mode.
+// In Postgres the keys are those RETURNING gives, in the order of the rows of the statement.
+func (repo *Repository[T]) InsertMany(ctx context.Context, vs []*T) ([]int64, error) {
+	if len(vs) == 0 {
+		return []int64{}, nil
+	}
+	cols := repo.valueColumns()
+	rows := make([][]interface{}, len(vs))
+	for i, v := range vs {
+		repo.touch(v, repo.Table.CreatedAt, repo.Table.UpdatedAt)
+		args, err := repo.values(v, cols)
+		if err != nil {
+			return nil, err
+		}
+		rows[i] = args
+	}
+	// the lengths of the statement without rows and of a row with its separator,
+	// a row being (?, ?, ..., ?): 3 bytes per column
+	headLen := len(`INSERT INTO  (  ) VALUES `) + len(repo.Table.Name) + len(strings.Join(cols, ", "))
+	rowLen := 3*len(cols) + 2
+	chunks := insertChunks(rows, headLen, rowLen, maxAllowedPacket, maxPlaceholders)
+
+	d := repo.dialect()
+	var ids []int64
+	err := repo.WithTx(ctx, nil, func(tx Tx) error {
+		// the func runs again when the transaction is retried
+		ids = make([]int64, 0, len(vs))
+		var step int64
+		if d == query.MySQL {
+			if err := tx.QueryRowContext(ctx, `SELECT @@auto_increment_increment `).Scan(&step); err != nil {
+				return err
+			}
+		}
+		for _, chunk := range chunks {
+			b := query.Insert(repo.Table.Name).Columns(cols...)
+			for _, r := range chunk {
+				b.Values(r...)
+			}
+			if d == query.Postgres {
+				b.Suffix(`RETURNING ` + repo.Table.PrimaryKey)
+			}
+			sqlstr, args, err := b.ToSQL(d)
+			if err != nil {
+				return err
+			}
+			if d == query.Postgres {
+				returned, err := queryIDs(ctx, tx, sqlstr, args)
+				if err != nil {
+					return err
+				}
+				if len(returned) != len(chunk) {
+					return fmt.Errorf("%w: expected %d, got %d", ErrUnexpectedRowCount, len(chunk), len(returned))
+				}
+				ids = append(ids, returned...)
+				continue
+			}
+			res, err := tx.ExecuteContext(ctx, sqlstr, args...)
+			if err != nil {
+				return err
+			}
+			first, err := res.LastInsertId()
+			if err != nil {
+				return err
+			}
+			rowsAffect, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if rowsAffect != int64(len(chunk)) {
+				return fmt.Errorf("%w: expected %d, got %d", ErrUnexpectedRowCount, len(chunk), rowsAffect)
+			}
+			for i := range chunk {
+				ids = append(ids, first+int64(i)*step)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// queryIDs is the integer first column of the rows of the query
+func queryIDs(ctx context.Context, e execer, sqlstr string, args []interface{}) (ids []int64, err error) {
+	rows, err := e.QueryContext(ctx, sqlstr, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil && err == nil {
+			ids, err = nil, cerr
+		}
+	}()
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// insertChunks splits rows so that the statement of each chunk, estimated from
+// the length of its head, rowLen per row and the size of the args, stays under
+// maxBytes and has at most maxArgs args. A chunk has at least one row.
+func insertChunks(rows [][]interface{}, headLen, rowLen, maxBytes, maxArgs int) [][][]interface{} {
+	chunks := [][][]interface{}{}
+	start, size, args := 0, headLen, 0
+	for i, r := range rows {
+		rowSize := rowLen
+		for _, a := range r {
+			rowSize += argSize(a)
+		}
+		if i > start && (size+rowSize > maxBytes || args+len(r) > maxArgs) {
+			chunks = append(chunks, rows[start:i])
+			start, size, args = i, headLen, 0
+		}
+		size += rowSize
+		args += len(r)
+	}
+	return append(chunks, rows[start:])
+}
+
+// argSize is about the bytes a in a statement packet takes
+func argSize(a interface{}) int {
+	// length-encoded values have a prefix of up to 9 bytes
+	switch v := a.(type) {
+	case string:
+		return len(v) + 9
+	case []byte:
+		return len(v) + 9
+	case sql.NullString:
+		return len(v.String) + 9
+	default:
+		return 16
+	}
+}
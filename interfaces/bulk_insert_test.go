@@ -0,0 +1,162 @@
+package interfaces
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// bulkMock is Mock whose transaction records statements and
+// reports one affected row per VALUES tuple
+type bulkMock struct {
+	Mock
+	queries *[]string
+	nextID  *int64
+}
+
+type bulkResult struct {
+	first int64
+	rows  int64
+}
+
+func (r bulkResult) LastInsertId() (int64, error) { return r.first, nil }
+func (r bulkResult) RowsAffected() (int64, error) { return r.rows, nil }
+
+func (m bulkMock) WithTx(ctx context.Context, opts *sql.TxOptions, fn func(Tx) error) error {
+	return fn(m)
+}
+
+func (m bulkMock) ExecuteContext(ctx context.Context, query string, args ...interface{}) (Result, error) {
+	*m.queries = append(*m.queries, query)
+	if m.errNo != 0 {
+		return m.Mock.ExecuteContext(ctx, query, args...)
+	}
+	rows := int64(strings.Count(query, "(")) - 1
+	r := bulkResult{first: *m.nextID, rows: rows}
+	*m.nextID += rows * 2
+	return r, nil
+}
+
+func TestInsertChunks(t *testing.T) {
+	rows := [][]interface{}{{"aaaa"}, {"bb"}, {"cccccc"}, {1}, {2}}
+	tests := []struct {
+		maxBytes int
+		maxArgs  int
+		sizes    []int
+	}{
+		{maxBytes: 1000, maxArgs: 100, sizes: []int{5}},
+		{maxBytes: 1000, maxArgs: 2, sizes: []int{2, 2, 1}},
+		// head 10 + rows of 13+3, 11+3, 15+3, 16+3, 16+3
+		{maxBytes: 50, maxArgs: 100, sizes: []int{2, 2, 1}},
+		// a row larger than the limit still goes alone
+		{maxBytes: 1, maxArgs: 100, sizes: []int{1, 1, 1, 1, 1}},
+	}
+	for i, test := range tests {
+		chunks := insertChunks(rows, 10, 3, test.maxBytes, test.maxArgs)
+		sizes := []int{}
+		for _, c := range chunks {
+			sizes = append(sizes, len(c))
+		}
+		if !reflect.DeepEqual(sizes, test.sizes) {
+			t.Errorf("%d, expected  %v, actual %v", i, test.sizes, sizes)
+		}
+	}
+
+	// placeholder limit of MySQL
+	many := make([][]interface{}, 40000)
+	for i := range many {
+		many[i] = []interface{}{i, i}
+	}
+	chunks := insertChunks(many, 10, 8, maxAllowedPacket, maxPlaceholders)
+	if len(chunks) != 2 || len(chunks[0]) != 32767 || len(chunks[1]) != 7233 {
+		t.Errorf("expected chunks of 32767 and 7233 rows, actual %d chunks", len(chunks))
+	}
+}
+
+func TestSQLRepository_InsertUsers(t *testing.T) {
+	tests := []struct {
+		m   bulkMock
+		in  []*User
+		ids []uint
+		err error
+	}{
+		{
+			m:   bulkMock{},
+			in:  []*User{{Email: "a@example.com"}, {Email: "b@example.com"}, {Email: "c@example.com"}},
+			ids: []uint{10, 12, 14},
+			err: nil,
+		},
+		{
+			m:   bulkMock{},
+			in:  []*User{},
+			ids: []uint{},
+			err: nil,
+		},
+		{
+			m:   bulkMock{Mock: Mock{errNo: 5}},
+			in:  []*User{{Email: "a@example.com"}},
+			err: errors.New("error execute"),
+		},
+	}
+	for i, test := range tests {
+		test.m.queries = &[]string{}
+		test.m.nextID = new(int64)
+		*test.m.nextID = 10
+		repo := SQLRepository{SQLhandler: test.m}
+		ids, err := repo.InsertUsers(context.Background(), test.in)
+		if test.err != nil {
+			if err == nil || err.Error() != test.err.Error() {
+				t.Errorf("%d, expected  %v, actual %v", i, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%d, expected  %v, actual %v", i, nil, err)
+		}
+		if !reflect.DeepEqual(ids, test.ids) {
+			t.Errorf("%d, expected  %v, actual %v", i, test.ids, ids)
+		}
+	}
+
+	m := bulkMock{queries: &[]string{}, nextID: new(int64)}
+	repo := SQLRepository{SQLhandler: m}
+	if _, err := repo.InsertUsers(context.Background(), []*User{{Email: "a"}, {Email: "b"}}); err != nil {
+		t.Fatalf("expected  %v, actual %v", nil, err)
+	}
+	query := "INSERT INTO users ( email, name, status, created_at, updated_at, deleted_at, version ) " +
+		"VALUES (?, ?, ?, ?, ?, ?, ?), (?, ?, ?, ?, ?, ?, ?) "
+	if len(*m.queries) != 1 || (*m.queries)[0] != query {
+		t.Errorf("expected  %v, actual %v", query, *m.queries)
+	}
+}
+
+func TestSQLRepository_InsertUsersPostgres(t *testing.T) {
+	tests := []struct {
+		rows [][]interface{}
+		ids  []uint
+		err  error
+	}{
+		{rows: [][]interface{}{{int64(7)}, {int64(3)}}, ids: []uint{7, 3}},
+		{rows: [][]interface{}{{int64(7)}}, err: ErrUnexpectedRowCount},
+	}
+	for i, test := range tests {
+		r := newPgRecorder(test.rows...)
+		repo := SQLRepository{SQLhandler: r}
+		ids, err := repo.InsertUsers(context.Background(), []*User{{Email: "a"}, {Email: "b"}})
+		if !errors.Is(err, test.err) {
+			t.Errorf("%d, expected  %v, actual %v", i, test.err, err)
+		}
+		if !reflect.DeepEqual(ids, test.ids) {
+			t.Errorf("%d, expected  %v, actual %v", i, test.ids, ids)
+		}
+		// no @@auto_increment_increment, the keys come from RETURNING
+		query := "INSERT INTO users ( email, name, status, created_at, updated_at, deleted_at, version ) " +
+			"VALUES ($1, $2, $3, $4, $5, $6, $7), ($8, $9, $10, $11, $12, $13, $14) RETURNING id "
+		if len(*r.queries) != 1 || (*r.queries)[0] != query {
+			t.Errorf("%d, expected  %v, actual %v", i, query, *r.queries)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package interfaces
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"sync"
+)
+
+// processCursorKey signs cursors when SQLRepository.CursorKey is not set
+var (
+	processCursorKey     []byte
+	processCursorKeyOnce sync.Once
+)
+
+// cursor is where the next page starts and the query it was issued for
+type cursor struct {
+	LastID      uint         `json:"id"`
+	Order       SortOrder    `json:"o"`
+	Email       string       `json:"e,omitempty"`
+	EmailPrefix string       `json:"p,omitempty"`
+	Deleted     deletedScope `json:"d,omitempty"`
+}
+
+// cursorKey is repo.CursorKey, or a key made once per process
+func (repo *SQLRepository) cursorKey() []byte {
+	if len(repo.CursorKey) > 0 {
+		return repo.CursorKey
+	}
+	processCursorKeyOnce.Do(func() {
+		processCursorKey = make([]byte, 32)
+		if _, err := rand.Read(processCursorKey); err != nil {
+			panic(err)
+		}
+	})
+	return processCursorKey
+}
+
+// encodeCursor is base64(json) "." base64(HMAC-SHA256 of json)
+func encodeCursor(key []byte, c cursor) string {
+	payload, _ := json.Marshal(c)
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// decodeCursor returns ErrInvalidCursor unless s was made by encodeCursor with key
+func decodeCursor(key []byte, s string) (cursor, error) {
+	c := cursor{}
+	p, m, ok := strings.Cut(s, ".")
+	if !ok {
+		return c, ErrInvalidCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(p)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	sum, err := base64.RawURLEncoding.DecodeString(m)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	if !hmac.Equal(sum, mac.Sum(nil)) {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
@@ -0,0 +1,44 @@
+package interfaces
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNotFound is returned when no row matched
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when a row with the same unique key exists
+	ErrConflict = errors.New("conflict")
+	// ErrConstraint is returned for foreign key, not null and check constraint violations
+	ErrConstraint = errors.New("constraint violation")
+	// ErrTimeout is returned when a statement or a lock wait timed out
+	ErrTimeout = errors.New("timeout")
+	// ErrLockTimeout is returned when a row lock was not granted within
+	// innodb_lock_wait_timeout. It is an ErrTimeout too.
+	ErrLockTimeout = fmt.Errorf("lock wait %w", ErrTimeout)
+	// ErrLockNotAvailable is returned when a NoWait read found the row locked
+	ErrLockNotAvailable = errors.New("lock not available")
+	// ErrUnexpectedRowCount is returned when a statement affected an unexpected number of rows
+	ErrUnexpectedRowCount = errors.New("unexpected row count")
+	// ErrStaleObject is returned when a row was updated by someone else since it was read
+	ErrStaleObject = errors.New("stale object")
+	// ErrInvalidCursor is returned for a page cursor that was not issued for the same query or was altered
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// DBError is one of the errors above (Kind) caused by a driver error (Err).
+// errors.Is matches both of them and errors.As reaches the driver error,
+// e.g. *mysql.MySQLError.
+type DBError struct {
+	Kind error
+	Err  error
+}
+
+func (e *DBError) Error() string {
+	return e.Kind.Error() + ": " + e.Err.Error()
+}
+
+func (e *DBError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
@@ -0,0 +1,41 @@
+package interfaces
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type driverError struct {
+	code int
+}
+
+func (e *driverError) Error() string {
+	return fmt.Sprintf("driver error %d", e.code)
+}
+
+func TestDBError(t *testing.T) {
+	cause := &driverError{code: 1062}
+	err := fmt.Errorf("insert user: %w", &DBError{Kind: ErrConflict, Err: cause})
+
+	if !errors.Is(err, ErrConflict) {
+		t.Errorf("expected errors.Is(err, ErrConflict)")
+	}
+	if errors.Is(err, ErrNotFound) {
+		t.Errorf("expected !errors.Is(err, ErrNotFound)")
+	}
+	var de *driverError
+	if !errors.As(err, &de) || de.code != 1062 {
+		t.Errorf("expected errors.As to reach the driver error, actual %v", de)
+	}
+	expected := "insert user: conflict: driver error 1062"
+	if err.Error() != expected {
+		t.Errorf("expected  %v, actual %v", expected, err.Error())
+	}
+
+	notFound := &DBError{Kind: ErrNotFound, Err: sql.ErrNoRows}
+	if !errors.Is(notFound, ErrNotFound) || !errors.Is(notFound, sql.ErrNoRows) {
+		t.Errorf("expected both kind and cause to match")
+	}
+}
@@ -0,0 +1,522 @@
+package interfaces
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/nakamura244/databasesql/interfaces/query"
+)
+
+// Table is metadata of the table a Repository reads and writes
+type Table struct {
+	Name string
+	// PrimaryKey is the auto increment primary key column
+	PrimaryKey string
+	// Columns are the columns to read and write, PrimaryKey included.
+	// When empty, every `db` column of the struct is used.
+	Columns []string
+	// CreatedAt and UpdatedAt are time.Time columns set to the current time
+	// in UTC by the insert and update methods, if not empty.
+	// CreatedAt is not changed by updates.
+	CreatedAt string
+	UpdatedAt string
+	// DeletedAt is the nullable time column of soft delete, if not empty.
+	// Delete then sets it instead of removing the row, and the finders,
+	// Update and Delete skip the rows that have it set.
+	DeletedAt string
+	// Version is the integer column of optimistic locking, if not empty.
+	// Update then only changes the row when it still has the version of v,
+	// and increments it.
+	Version string
+	// UniqueKey is the columns of the unique key Upsert updates on in Postgres,
+	// which needs it named. MySQL updates on any unique key.
+	UniqueKey []string
+}
+
+// now is the time stamped on rows, in UTC and at the microsecond precision of DATETIME(6)
+var now = func() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// Repository is CRUD for the struct T stored in one table.
+// The columns of T are matched to its fields like ScanStruct.
+type Repository[T any] struct {
+	SQLhandler
+	Table Table
+}
+
+// NewRepository is Repository for T in table
+func NewRepository[T any](h SQLhandler, table Table) *Repository[T] {
+	return &Repository[T]{SQLhandler: h, Table: table}
+}
+
+// Criteria selects the rows of Find and Iterate
+type Criteria struct {
+	// Where are conditions the rows must all meet
+	Where   []query.Cond
+	OrderBy string // e.g. "id DESC"
+	Limit   int    // no limit when 0
+	Offset  int
+	// Lock locks the selected rows, see LockMode. It only lasts in a transaction.
+	Lock LockMode
+}
+
+// FindByID returns ErrNotFound when there is no row with id
+func (repo *Repository[T]) FindByID(ctx context.Context, id interface{}, opts ...QueryOption) (*T, error) {
+	return repo.findByID(ctx, repo, id, 0, opts)
+}
+
+// FindByIDLocked is FindByID in tx that locks the row with mode until tx ends
+func (repo *Repository[T]) FindByIDLocked(ctx context.Context, tx Tx, id interface{}, mode LockMode, opts ...QueryOption) (*T, error) {
+	if err := mode.validate(); err != nil {
+		return nil, err
+	}
+	return repo.findByID(ctx, tx, id, mode, opts)
+}
+
+func (repo *Repository[T]) findByID(ctx context.Context, e execer, id interface{}, mode LockMode, opts []QueryOption) (v *T, err error) {
+	sqlstr, args, err := repo.selectSQL(Criteria{
+		Where: []query.Cond{query.Eq(repo.Table.PrimaryKey, id)},
+		Lock:  mode,
+	}, opts)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := e.QueryContext(ctx, sqlstr, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil && err == nil {
+			v, err = nil, cerr
+		}
+	}()
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrNotFound
+	}
+	v = new(T)
+	if err = ScanStruct(rows, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (repo *Repository[T]) FindAll(ctx context.Context, opts ...QueryOption) ([]*T, error) {
+	return repo.Find(ctx, Criteria{}, opts...)
+}
+
+// Find is the rows selected by c
+func (repo *Repository[T]) Find(ctx context.Context, c Criteria, opts ...QueryOption) (vs []*T, err error) {
+	sqlstr, args, err := repo.selectSQL(c, opts)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := repo.QueryContext(ctx, sqlstr, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil && err == nil {
+			vs, err = nil, cerr
+		}
+	}()
+	vs = []*T{}
+	if err = ScanAll(rows, &vs); err != nil {
+		return nil, err
+	}
+	return vs, nil
+}
+
+// Iterate is Find streamed through an Iterator instead of a slice
+func (repo *Repository[T]) Iterate(ctx context.Context, c Criteria, opts ...QueryOption) (*Iterator[T], error) {
+	sqlstr, args, err := repo.selectSQL(c, opts)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := repo.QueryContext(ctx, sqlstr, args...)
+	if err != nil {
+		return nil, err
+	}
+	return newIterator[T](ctx, rows)
+}
+
+// selectSQL is the SELECT statement of c and its args
+func (repo *Repository[T]) selectSQL(c Criteria, opts []QueryOption) (string, []interface{}, error) {
+	b := query.Select(repo.columns()...).
+		From(repo.Table.Name).
+		Where(repo.scope(opts)).
+		Where(c.Where...).
+		Limit(c.Limit).
+		Offset(c.Offset)
+	if c.OrderBy != "" {
+		b.OrderBy(c.OrderBy)
+	}
+	if c.Lock != 0 {
+		b.Suffix(c.Lock.clause(repo.dialect()))
+	}
+	return b.ToSQL(repo.dialect())
+}
+
+// Insert inserts v without its primary key and returns the generated one
+func (repo *Repository[T]) Insert(ctx context.Context, v *T) (int64, error) {
+	return repo.insert(ctx, repo, v)
+}
+
+// InsertWithTx is Insert in a transaction that is rolled back unless exactly one row was inserted
+func (repo *Repository[T]) InsertWithTx(ctx context.Context, v *T) (int64, error) {
+	var lastID int64
+	err := repo.WithTx(ctx, nil, func(tx Tx) error {
+		res, err := repo.insertResult(ctx, tx, v)
+		if err != nil {
+			return err
+		}
+		lastID, err = res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		rowsAffect, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffect != 1 {
+			return fmt.Errorf("%w: expected 1, got %d", ErrUnexpectedRowCount, rowsAffect)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return lastID, nil
+}
+
+// Upsert inserts v, or updates the row with the same unique key as v when there is one,
+// and returns the primary key of the row and whether it was inserted.
+func (repo *Repository[T]) Upsert(ctx context.Context, v *T) (int64, bool, error) {
+	repo.touch(v, repo.Table.CreatedAt, repo.Table.UpdatedAt)
+	cols := repo.valueColumns()
+	args, err := repo.values(v, cols)
+	if err != nil {
+		return 0, false, err
+	}
+	if repo.dialect() == query.Postgres {
+		return repo.upsertPostgres(ctx, cols, args)
+	}
+	// LAST_INSERT_ID(pk) makes LastInsertId the key of the updated row
+	set := []string{repo.Table.PrimaryKey + ` = LAST_INSERT_ID(` + repo.Table.PrimaryKey + `)`}
+	for _, c := range repo.updateColumns() {
+		set = append(set, c+` = VALUES(`+c+`)`)
+	}
+	if repo.Table.DeletedAt != "" {
+		// a soft deleted row with the same key comes back
+		set = append(set, repo.Table.DeletedAt+` = NULL`)
+	}
+	if repo.Table.Version != "" {
+		set = append(set, repo.Table.Version+` = `+repo.Table.Version+` + 1`)
+	}
+	sqlstr, args, err := query.Insert(repo.Table.Name).
+		Columns(cols...).
+		Values(args...).
+		Suffix(`ON DUPLICATE KEY UPDATE ` + strings.Join(set, ", ")).
+		ToSQL(query.MySQL)
+	if err != nil {
+		return 0, false, err
+	}
+	res, err := repo.ExecuteContext(ctx, sqlstr, args...)
+	if err != nil {
+		return 0, false, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, false, err
+	}
+	rowsAffect, err := res.RowsAffected()
+	if err != nil {
+		return 0, false, err
+	}
+	// MySQL counts 1 for an inserted row, 2 for an updated one
+	// and 0 for one that already had the values
+	switch rowsAffect {
+	case 1:
+		return id, true, nil
+	case 0, 2:
+		return id, false, nil
+	default:
+		return 0, false, fmt.Errorf("%w: expected 0, 1 or 2, got %d", ErrUnexpectedRowCount, rowsAffect)
+	}
+}
+
+// upsertPostgres is Upsert with ON CONFLICT on Table.UniqueKey
+func (repo *Repository[T]) upsertPostgres(ctx context.Context, cols []string, args []interface{}) (int64, bool, error) {
+	if len(repo.Table.UniqueKey) == 0 {
+		return 0, false, fmt.Errorf("interfaces: table %s has no UniqueKey to upsert on", repo.Table.Name)
+	}
+	// the row being updated is referred to by the table name without its schema
+	target := repo.Table.Name[strings.LastIndex(repo.Table.Name, ".")+1:]
+	set := []string{repo.Table.PrimaryKey + ` = ` + target + `.` + repo.Table.PrimaryKey}
+	for _, c := range repo.updateColumns() {
+		set = append(set, c+` = EXCLUDED.`+c)
+	}
+	if repo.Table.DeletedAt != "" {
+		// a soft deleted row with the same key comes back
+		set = append(set, repo.Table.DeletedAt+` = NULL`)
+	}
+	if repo.Table.Version != "" {
+		set = append(set, repo.Table.Version+` = `+target+`.`+repo.Table.Version+` + 1`)
+	}
+	// xmax is 0 for a row the statement inserted and set for one it updated
+	sqlstr, args, err := query.Insert(repo.Table.Name).
+		Columns(cols...).
+		Values(args...).
+		Suffix(`ON CONFLICT (` + strings.Join(repo.Table.UniqueKey, ", ") + `) DO UPDATE SET ` + strings.Join(set, ", ") +
+			` RETURNING ` + repo.Table.PrimaryKey + `, xmax = 0`).
+		ToSQL(query.Postgres)
+	if err != nil {
+		return 0, false, err
+	}
+	var id int64
+	var inserted bool
+	if err := repo.QueryRowContext(ctx, sqlstr, args...).Scan(&id, &inserted); err != nil {
+		return 0, false, err
+	}
+	return id, inserted, nil
+}
+
+// Update updates the row with the primary key of v. It returns ErrNotFound when there is no such row,
+// and ErrStaleObject when the table has Version and the row was updated since v was read.
+func (repo *Repository[T]) Update(ctx context.Context, v *T) error {
+	if err := repo.update(ctx, repo, v); err != nil {
+		return err
+	}
+	repo.bumpVersion(v)
+	return nil
+}
+
+// UpdateWithTx is Update in a transaction. Version of v is incremented once the
+// transaction is committed, so a retried transaction updates with the version v was read with.
+func (repo *Repository[T]) UpdateWithTx(ctx context.Context, v *T) error {
+	err := repo.WithTx(ctx, nil, func(tx Tx) error {
+		return repo.update(ctx, tx, v)
+	})
+	if err != nil {
+		return err
+	}
+	repo.bumpVersion(v)
+	return nil
+}
+
+// Delete deletes the row with id. It returns ErrNotFound when there is no such row.
+func (repo *Repository[T]) Delete(ctx context.Context, id interface{}) error {
+	return repo.delete(ctx, repo, id)
+}
+
+func (repo *Repository[T]) DeleteWithTx(ctx context.Context, id interface{}) error {
+	return repo.WithTx(ctx, nil, func(tx Tx) error {
+		return repo.delete(ctx, tx, id)
+	})
+}
+
+func (repo *Repository[T]) insert(ctx context.Context, e execer, v *T) (int64, error) {
+	res, err := repo.insertResult(ctx, e, v)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (repo *Repository[T]) insertResult(ctx context.Context, e execer, v *T) (Result, error) {
+	repo.touch(v, repo.Table.CreatedAt, repo.Table.UpdatedAt)
+	cols := repo.valueColumns()
+	args, err := repo.values(v, cols)
+	if err != nil {
+		return nil, err
+	}
+	b := query.Insert(repo.Table.Name).
+		Columns(cols...).
+		Values(args...)
+	if repo.dialect() == query.Postgres {
+		// Postgres has no LastInsertId, the handler reads it from RETURNING
+		b.Suffix(`RETURNING ` + repo.Table.PrimaryKey)
+	}
+	sqlstr, args, err := b.ToSQL(repo.dialect())
+	if err != nil {
+		return nil, err
+	}
+	return e.ExecuteContext(ctx, sqlstr, args...)
+}
+
+func (repo *Repository[T]) update(ctx context.Context, e execer, v *T) error {
+	repo.touch(v, repo.Table.UpdatedAt)
+	cols := repo.updateColumns()
+	args, err := repo.values(v, append(cols, repo.Table.PrimaryKey))
+	if err != nil {
+		return err
+	}
+	id := args[len(args)-1]
+	b := query.Update(repo.Table.Name)
+	for i, c := range cols {
+		b.Set(c, args[i])
+	}
+	b.Where(query.Eq(repo.Table.PrimaryKey, id))
+	if repo.Table.Version != "" {
+		version, err := repo.values(v, []string{repo.Table.Version})
+		if err != nil {
+			return err
+		}
+		b.SetExpr(repo.Table.Version, repo.Table.Version+` + 1`).
+			Where(query.Eq(repo.Table.Version, version[0]))
+	}
+	sqlstr, args, err := b.Where(repo.notDeleted()).ToSQL(repo.dialect())
+	if err != nil {
+		return err
+	}
+	res, err := e.ExecuteContext(ctx, sqlstr, args...)
+	if err != nil {
+		return err
+	}
+	rowsAffect, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffect == 0 {
+		err := repo.exists(ctx, e, id)
+		if err == nil && repo.Table.Version != "" {
+			// the row is there with another version
+			return ErrStaleObject
+		}
+		// MySQL does not count a matched row whose values did not change
+		return err
+	}
+	return nil
+}
+
+// bumpVersion increments the Table.Version field of v like Update did to the row
+func (repo *Repository[T]) bumpVersion(v *T) {
+	rv := reflect.ValueOf(v).Elem()
+	index, ok := fieldsOf(rv.Type()).index[repo.Table.Version]
+	if repo.Table.Version == "" || !ok {
+		return
+	}
+	switch f := fieldByIndex(rv, index); f.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f.SetInt(f.Int() + 1)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		f.SetUint(f.Uint() + 1)
+	}
+}
+
+func (repo *Repository[T]) delete(ctx context.Context, e execer, id interface{}) error {
+	if repo.Table.DeletedAt != "" {
+		return repo.softDelete(ctx, e, id)
+	}
+	sqlstr, args, err := query.Delete(repo.Table.Name).
+		Where(query.Eq(repo.Table.PrimaryKey, id)).
+		ToSQL(repo.dialect())
+	if err != nil {
+		return err
+	}
+	res, err := e.ExecuteContext(ctx, sqlstr, args...)
+	if err != nil {
+		return err
+	}
+	rowsAffect, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffect == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// exists returns ErrNotFound when there is no row with id
+func (repo *Repository[T]) exists(ctx context.Context, e execer, id interface{}) error {
+	sqlstr, args, err := query.Select(`1`).
+		From(repo.Table.Name).
+		Where(query.Eq(repo.Table.PrimaryKey, id), repo.notDeleted()).
+		ToSQL(repo.dialect())
+	if err != nil {
+		return err
+	}
+	var one int
+	err = e.QueryRowContext(ctx, sqlstr, args...).Scan(&one)
+	if errors.Is(err, ErrNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// dialect is the dialect of the handler, MySQL unless it is a Dialecter
+func (repo *Repository[T]) dialect() query.Dialect {
+	if d, ok := repo.SQLhandler.(Dialecter); ok {
+		return d.Dialect()
+	}
+	return query.MySQL
+}
+
+// columns is Table.Columns or every column of T
+func (repo *Repository[T]) columns() []string {
+	if len(repo.Table.Columns) > 0 {
+		return repo.Table.Columns
+	}
+	return fieldsOf(reflect.TypeOf((*T)(nil)).Elem()).columns
+}
+
+// valueColumns is columns without the primary key
+func (repo *Repository[T]) valueColumns() []string {
+	cols := []string{}
+	for _, c := range repo.columns() {
+		if c != repo.Table.PrimaryKey {
+			cols = append(cols, c)
+		}
+	}
+	return cols
+}
+
+// updateColumns is valueColumns without Table.CreatedAt, Table.DeletedAt and Table.Version
+func (repo *Repository[T]) updateColumns() []string {
+	cols := []string{}
+	for _, c := range repo.valueColumns() {
+		if c != repo.Table.CreatedAt && c != repo.Table.DeletedAt && c != repo.Table.Version {
+			cols = append(cols, c)
+		}
+	}
+	return cols
+}
+
+// touch sets the time.Time fields of v for cols to now
+func (repo *Repository[T]) touch(v *T, cols ...string) {
+	rv := reflect.ValueOf(v).Elem()
+	fields := fieldsOf(rv.Type()).index
+	t := now()
+	for _, c := range cols {
+		index, ok := fields[c]
+		if c == "" || !ok {
+			continue
+		}
+		if f := fieldByIndex(rv, index); f.Type() == reflect.TypeOf(t) {
+			f.Set(reflect.ValueOf(t))
+		}
+	}
+}
+
+// values is the fields of v for cols
+func (repo *Repository[T]) values(v *T, cols []string) ([]interface{}, error) {
+	rv := reflect.ValueOf(v).Elem()
+	fields := fieldsOf(rv.Type()).index
+	args := make([]interface{}, len(cols))
+	for i, c := range cols {
+		index, ok := fields[c]
+		if !ok {
+			return nil, fmt.Errorf("interfaces: no field for column %q in %s", c, rv.Type())
+		}
+		args[i] = fieldValue(rv, index)
+	}
+	return args, nil
+}
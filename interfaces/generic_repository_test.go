@@ -0,0 +1,324 @@
+package interfaces
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nakamura244/databasesql/interfaces/query"
+)
+
+type item struct {
+	ItemID uint           `db:"item_id"`
+	Name   string         `db:"name"`
+	Price  int            `db:"price"`
+	Note   sql.NullString `db:"note"`
+	Memo   string         `db:"-"`
+}
+
+// sqlRecorder is Mock that records the statements run through it
+type sqlRecorder struct {
+	Mock
+	queries *[]string
+	args    *[][]interface{}
+}
+
+func newSQLRecorder(m Mock) sqlRecorder {
+	return sqlRecorder{Mock: m, queries: &[]string{}, args: &[][]interface{}{}}
+}
+
+func (r sqlRecorder) record(query string, args []interface{}) {
+	*r.queries = append(*r.queries, query)
+	*r.args = append(*r.args, args)
+}
+
+func (r sqlRecorder) ExecuteContext(ctx context.Context, query string, args ...interface{}) (Result, error) {
+	r.record(query, args)
+	return r.Mock.ExecuteContext(ctx, query, args...)
+}
+
+func (r sqlRecorder) QueryContext(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+	r.record(query, args)
+	return r.Mock.QueryContext(ctx, query, args...)
+}
+
+func (r sqlRecorder) QueryRowContext(ctx context.Context, query string, args ...interface{}) Row {
+	r.record(query, args)
+	return r.Mock.QueryRowContext(ctx, query, args...)
+}
+
+// pgRecorder is sqlRecorder of a Postgres handler whose queries return rows.
+// WithTx runs the func with the recorder as the transaction.
+type pgRecorder struct {
+	sqlRecorder
+	rows [][]interface{}
+}
+
+func newPgRecorder(rows ...[]interface{}) pgRecorder {
+	return pgRecorder{sqlRecorder: newSQLRecorder(Mock{}), rows: rows}
+}
+
+func (r pgRecorder) Dialect() query.Dialect {
+	return query.Postgres
+}
+
+func (r pgRecorder) QueryContext(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+	r.record(query, args)
+	return closeCounter{rowsMock: newRowsMock(nil, r.rows...), closed: new(int)}, nil
+}
+
+func (r pgRecorder) QueryRowContext(ctx context.Context, query string, args ...interface{}) Row {
+	rows, _ := r.QueryContext(ctx, query, args...)
+	rows.Next()
+	return rows
+}
+
+func (r pgRecorder) WithTx(ctx context.Context, opts *sql.TxOptions, fn func(Tx) error) error {
+	return fn(r)
+}
+
+func TestRepository_SQL(t *testing.T) {
+	table := Table{Name: "items", PrimaryKey: "item_id"}
+	tests := []struct {
+		run   func(repo *Repository[item]) error
+		query string
+		args  []interface{}
+	}{
+		{
+			run: func(repo *Repository[item]) error {
+				_, err := repo.Insert(context.Background(), &item{Name: "pen", Price: 100, Memo: "x"})
+				return err
+			},
+			query: "INSERT INTO items ( name, price, note ) VALUES (?, ?, ?) ",
+			args:  []interface{}{"pen", 100, sql.NullString{}},
+		},
+		{
+			run: func(repo *Repository[item]) error {
+				return repo.Update(context.Background(), &item{ItemID: 3, Name: "pen", Price: 120})
+			},
+			query: "UPDATE items SET name = ?, price = ?, note = ? WHERE item_id = ? ",
+			args:  []interface{}{"pen", 120, sql.NullString{}, uint(3)},
+		},
+		{
+			run: func(repo *Repository[item]) error {
+				return repo.Delete(context.Background(), 3)
+			},
+			query: "DELETE FROM items WHERE item_id = ? ",
+			args:  []interface{}{3},
+		},
+	}
+	for i, test := range tests {
+		r := newSQLRecorder(Mock{})
+		if err := test.run(NewRepository[item](r, table)); err != nil {
+			t.Fatalf("%d, expected  %v, actual %v", i, nil, err)
+		}
+		if (*r.queries)[0] != test.query {
+			t.Errorf("%d, expected  %v, actual %v", i, test.query, (*r.queries)[0])
+		}
+		if !reflect.DeepEqual((*r.args)[0], test.args) {
+			t.Errorf("%d, expected  %v, actual %v", i, test.args, (*r.args)[0])
+		}
+	}
+}
+
+func TestRepository_SQLPostgres(t *testing.T) {
+	table := Table{Name: "items", PrimaryKey: "item_id"}
+	tests := []struct {
+		run   func(repo *Repository[item]) error
+		query string
+		args  []interface{}
+	}{
+		{
+			run: func(repo *Repository[item]) error {
+				_, err := repo.Insert(context.Background(), &item{Name: "pen", Price: 100})
+				return err
+			},
+			// the handler reads LastInsertId from RETURNING of the primary key
+			query: "INSERT INTO items ( name, price, note ) VALUES ($1, $2, $3) RETURNING item_id ",
+			args:  []interface{}{"pen", 100, sql.NullString{}},
+		},
+		{
+			run: func(repo *Repository[item]) error {
+				return repo.Update(context.Background(), &item{ItemID: 3, Name: "pen", Price: 120})
+			},
+			query: "UPDATE items SET name = $1, price = $2, note = $3 WHERE item_id = $4 ",
+			args:  []interface{}{"pen", 120, sql.NullString{}, uint(3)},
+		},
+		{
+			run: func(repo *Repository[item]) error {
+				return repo.Delete(context.Background(), 3)
+			},
+			query: "DELETE FROM items WHERE item_id = $1 ",
+			args:  []interface{}{3},
+		},
+		{
+			run: func(repo *Repository[item]) error {
+				_, err := repo.Find(context.Background(), Criteria{Where: []query.Cond{query.Gt("price", 100)}, Limit: 10})
+				return err
+			},
+			query: "SELECT item_id, name, price, note FROM items WHERE price > $1 LIMIT $2 ",
+			args:  []interface{}{100, 10},
+		},
+	}
+	for i, test := range tests {
+		r := newPgRecorder()
+		if err := test.run(NewRepository[item](r, table)); err != nil {
+			t.Fatalf("%d, expected  %v, actual %v", i, nil, err)
+		}
+		if (*r.queries)[0] != test.query {
+			t.Errorf("%d, expected  %v, actual %v", i, test.query, (*r.queries)[0])
+		}
+		if !reflect.DeepEqual((*r.args)[0], test.args) {
+			t.Errorf("%d, expected  %v, actual %v", i, test.args, (*r.args)[0])
+		}
+	}
+}
+
+func TestRepository_UpsertPostgres(t *testing.T) {
+	tests := []struct {
+		rows     [][]interface{}
+		id       uint
+		inserted bool
+	}{
+		{rows: [][]interface{}{{int64(5), true}}, id: 5, inserted: true},
+		{rows: [][]interface{}{{int64(6), false}}, id: 6, inserted: false},
+	}
+	for i, test := range tests {
+		r := newPgRecorder(test.rows...)
+		m := SQLRepository{SQLhandler: r}
+		id, inserted, err := m.UpsertUserByEmail(context.Background(), &User{Email: "email@example.com"})
+		if err != nil {
+			t.Fatalf("%d, expected  %v, actual %v", i, nil, err)
+		}
+		if id != test.id || inserted != test.inserted {
+			t.Errorf("%d, expected %v %v, got %v %v", i, test.id, test.inserted, id, inserted)
+		}
+		query := "INSERT INTO users ( email, name, status, created_at, updated_at, deleted_at, version ) " +
+			"VALUES ($1, $2, $3, $4, $5, $6, $7) ON CONFLICT (email) DO UPDATE SET id = users.id, " +
+			"email = EXCLUDED.email, name = EXCLUDED.name, status = EXCLUDED.status, updated_at = EXCLUDED.updated_at, " +
+			"deleted_at = NULL, version = users.version + 1 RETURNING id, xmax = 0 "
+		if (*r.queries)[0] != query {
+			t.Errorf("%d, expected  %v, actual %v", i, query, (*r.queries)[0])
+		}
+	}
+
+	// Postgres needs the unique key named
+	repo := NewRepository[item](newPgRecorder(), Table{Name: "items", PrimaryKey: "item_id"})
+	if _, _, err := repo.Upsert(context.Background(), &item{Name: "pen"}); err == nil {
+		t.Errorf("expected error for a table without UniqueKey")
+	}
+}
+
+func TestRepository_FindByID(t *testing.T) {
+	r := newSQLRecorder(Mock{})
+	repo := NewRepository[User](r, userTable)
+	u, err := repo.FindByID(context.Background(), uint(2))
+	if err != nil {
+		t.Fatalf("expected  %v, actual %v", nil, err)
+	}
+	expected := mockUser()
+	if !reflect.DeepEqual(u, expected) {
+		t.Errorf("expected %+v, got %+v", expected, u)
+	}
+	query := "SELECT " + userColumns + " FROM users WHERE deleted_at IS NULL AND id = ? "
+	if (*r.queries)[0] != query {
+		t.Errorf("expected  %v, actual %v", query, (*r.queries)[0])
+	}
+
+	if _, err := NewRepository[User](Mock{errNo: 4}, userTable).FindByID(context.Background(), uint(2)); err != ErrNotFound {
+		t.Errorf("expected  %v, actual %v", ErrNotFound, err)
+	}
+}
+
+func TestRepository_InvalidTable(t *testing.T) {
+	r := newSQLRecorder(Mock{})
+	repo := NewRepository[item](r, Table{Name: "items; DROP TABLE items", PrimaryKey: "item_id"})
+	if _, err := repo.FindByID(context.Background(), 1); err == nil {
+		t.Errorf("expected an error, actual %v", err)
+	}
+	if err := repo.Delete(context.Background(), 1); err == nil {
+		t.Errorf("expected an error, actual %v", err)
+	}
+	if len(*r.queries) != 0 {
+		t.Errorf("expected no query, actual %v", *r.queries)
+	}
+}
+
+func TestRepository_UpdateUnchanged(t *testing.T) {
+	// without a version column a matched but unchanged row is not an error
+	r := newSQLRecorder(Mock{errNo: 11})
+	err := NewRepository[item](r, Table{Name: "items", PrimaryKey: "item_id"}).Update(context.Background(), &item{ItemID: 9})
+	if err != nil {
+		t.Errorf("expected  %v, actual %v", nil, err)
+	}
+}
+
+func TestRepository_UpdateNotFound(t *testing.T) {
+	r := newSQLRecorder(Mock{errNo: 11, err: &DBError{Kind: ErrNotFound, Err: sql.ErrNoRows}})
+	err := NewRepository[item](r, Table{Name: "items", PrimaryKey: "item_id"}).Update(context.Background(), &item{ItemID: 9})
+	if err != ErrNotFound {
+		t.Errorf("expected  %v, actual %v", ErrNotFound, err)
+	}
+	query := "SELECT 1 FROM items WHERE item_id = ? "
+	if len(*r.queries) != 2 || (*r.queries)[1] != query {
+		t.Errorf("expected  %v, actual %v", query, *r.queries)
+	}
+}
+
+func TestRepository_Timestamps(t *testing.T) {
+	stamp := time.Date(2020, 1, 2, 3, 4, 5, 6000, time.UTC)
+	defer func(f func() time.Time) { now = f }(now)
+	now = func() time.Time { return stamp }
+
+	created := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := newSQLRecorder(Mock{})
+	repo := SQLRepository{SQLhandler: r}
+
+	u := &User{Email: "email@example.com"}
+	if _, err := repo.InsertUser(context.Background(), u); err != nil {
+		t.Fatalf("expected  %v, actual %v", nil, err)
+	}
+	if !u.CreatedAt.Equal(stamp) || !u.UpdatedAt.Equal(stamp) || u.CreatedAt.Location() != time.UTC {
+		t.Errorf("expected timestamps %v, actual %v %v", stamp, u.CreatedAt, u.UpdatedAt)
+	}
+	args := []interface{}{"email@example.com", sql.NullString{}, UserStatusActive, stamp, stamp, sql.NullTime{}, uint(0)}
+	if !reflect.DeepEqual((*r.args)[0], args) {
+		t.Errorf("expected  %v, actual %v", args, (*r.args)[0])
+	}
+
+	u = &User{ID: 3, Email: "email@example.com", Status: UserStatusSuspended, CreatedAt: created, Version: 4}
+	if err := repo.UpdateUser(context.Background(), u); err != nil {
+		t.Fatalf("expected  %v, actual %v", nil, err)
+	}
+	if !u.CreatedAt.Equal(created) || !u.UpdatedAt.Equal(stamp) {
+		t.Errorf("expected only updated_at to change, actual %v %v", u.CreatedAt, u.UpdatedAt)
+	}
+	query := "UPDATE users SET email = ?, name = ?, status = ?, updated_at = ?, version = version + 1 " +
+		"WHERE id = ? AND version = ? AND deleted_at IS NULL "
+	if (*r.queries)[1] != query {
+		t.Errorf("expected  %v, actual %v", query, (*r.queries)[1])
+	}
+	args = []interface{}{"email@example.com", sql.NullString{}, UserStatusSuspended, stamp, uint(3), uint(4)}
+	if !reflect.DeepEqual((*r.args)[1], args) {
+		t.Errorf("expected  %v, actual %v", args, (*r.args)[1])
+	}
+}
+
+func TestUserStatus_String(t *testing.T) {
+	tests := []struct {
+		s UserStatus
+		r string
+	}{
+		{s: UserStatusActive, r: "active"},
+		{s: UserStatusInactive, r: "inactive"},
+		{s: UserStatusSuspended, r: "suspended"},
+		{s: UserStatus(9), r: "UserStatus(9)"},
+	}
+	for i, test := range tests {
+		if test.s.String() != test.r {
+			t.Errorf("%d, expected  %v, actual %v", i, test.r, test.s.String())
+		}
+	}
+}
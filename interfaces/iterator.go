@@ -0,0 +1,107 @@
+package interfaces
+
+import (
+	"context"
+	"errors"
+	"reflect"
+)
+
+// Iterator streams the rows of a query into T one at a time.
+// It holds a connection until it is closed, which happens when Next returns
+// false or Close is called. Close it when stopping early.
+type Iterator[T any] struct {
+	ctx    context.Context
+	rows   Rows
+	cols   []string
+	fields map[string][]int
+	cur    *T
+	err    error
+
+	closed   bool
+	closeErr error
+}
+
+// newIterator is Iterator over rows, closing rows when it can not be made
+func newIterator[T any](ctx context.Context, rows Rows) (*Iterator[T], error) {
+	cols, err := rows.Columns()
+	if err != nil {
+		rows.Close()
+		return nil, err
+	}
+	return &Iterator[T]{
+		ctx:    ctx,
+		rows:   rows,
+		cols:   cols,
+		fields: fieldsOf(reflect.TypeOf((*T)(nil)).Elem()).index,
+	}, nil
+}
+
+// Next scans the next row. It returns false at the end of the rows, on an error
+// and when the context is done; Err tells them apart.
+func (it *Iterator[T]) Next() bool {
+	if it.closed || it.err != nil {
+		return false
+	}
+	if err := it.ctx.Err(); err != nil {
+		return it.fail(err)
+	}
+	if !it.rows.Next() {
+		return it.fail(it.rows.Err())
+	}
+	v := new(T)
+	targets, err := scanTargets(reflect.ValueOf(v).Elem(), it.cols, it.fields)
+	if err != nil {
+		return it.fail(err)
+	}
+	if err := it.rows.Scan(targets...); err != nil {
+		return it.fail(err)
+	}
+	it.cur = v
+	return true
+}
+
+// Value is the row scanned by the last Next
+func (it *Iterator[T]) Value() *T {
+	return it.cur
+}
+
+// Err is the error that stopped the iteration, nil at the end of the rows
+func (it *Iterator[T]) Err() error {
+	return it.err
+}
+
+// Close closes the rows. It can be called more than once.
+func (it *Iterator[T]) Close() error {
+	if !it.closed {
+		it.closed = true
+		it.cur = nil
+		it.closeErr = it.rows.Close()
+	}
+	return it.closeErr
+}
+
+// Each calls fn for every row until fn returns an error, and closes it even
+// when fn panics. An error of Close is joined to the returned error.
+func (it *Iterator[T]) Each(fn func(*T) error) (err error) {
+	defer func() {
+		if cerr := it.Close(); cerr != nil && cerr != err {
+			err = errors.Join(err, cerr)
+		}
+	}()
+	for it.Next() {
+		if err := fn(it.Value()); err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
+
+// fail ends the iteration with err, or with the Close error when err is nil
+func (it *Iterator[T]) fail(err error) bool {
+	cerr := it.Close()
+	if err == nil {
+		err = cerr
+	}
+	it.err = err
+	return false
+}
@@ -0,0 +1,173 @@
+package interfaces
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// closeCounter is rowsMock that counts Close calls
+type closeCounter struct {
+	rowsMock
+	closed *int
+	err    error
+}
+
+func (r closeCounter) Close() error {
+	*r.closed++
+	return r.err
+}
+
+func newCloseCounter(n int) closeCounter {
+	values := [][]interface{}{}
+	for i := 1; i <= n; i++ {
+		values = append(values, []interface{}{uint(i), "test string"})
+	}
+	return closeCounter{rowsMock: newRowsMock([]string{"id", "email"}, values...), closed: new(int)}
+}
+
+func TestIterator_Each(t *testing.T) {
+	stop := errors.New("stop")
+	tests := []struct {
+		rows   closeCounter
+		cancel bool
+		stopAt uint
+		seen   int
+		err    error
+	}{
+		{rows: newCloseCounter(3), seen: 3, err: nil},
+		{rows: newCloseCounter(0), seen: 0, err: nil},
+		{rows: newCloseCounter(3), stopAt: 2, seen: 2, err: stop},
+		{rows: newCloseCounter(3), cancel: true, seen: 0, err: context.Canceled},
+	}
+	for i, test := range tests {
+		ctx, cancel := context.WithCancel(context.Background())
+		if test.cancel {
+			cancel()
+		}
+		it, err := newIterator[User](ctx, test.rows)
+		if err != nil {
+			t.Fatalf("%d, expected  %v, actual %v", i, nil, err)
+		}
+		seen := 0
+		err = it.Each(func(u *User) error {
+			seen++
+			if u.ID == test.stopAt {
+				return stop
+			}
+			return nil
+		})
+		cancel()
+		if err != test.err {
+			t.Errorf("%d, expected  %v, actual %v", i, test.err, err)
+		}
+		if seen != test.seen {
+			t.Errorf("%d, expected %d rows, actual %d", i, test.seen, seen)
+		}
+		if *test.rows.closed != 1 {
+			t.Errorf("%d, expected rows closed once, actual %d", i, *test.rows.closed)
+		}
+		if it.Next() {
+			t.Errorf("%d, expected Next after the end to be false", i)
+		}
+		it.Close()
+		if *test.rows.closed != 1 {
+			t.Errorf("%d, expected Close to be idempotent, actual %d", i, *test.rows.closed)
+		}
+	}
+}
+
+func TestIterator_CloseError(t *testing.T) {
+	rows := newCloseCounter(1)
+	rows.err = errors.New("error close")
+	it, _ := newIterator[User](context.Background(), rows)
+	for it.Next() {
+		if it.Value().ID != 1 {
+			t.Errorf("expected  %v, actual %v", 1, it.Value().ID)
+		}
+	}
+	if it.Err() == nil || it.Err().Error() != "error close" {
+		t.Errorf("expected  %v, actual %v", rows.err, it.Err())
+	}
+}
+
+func TestIterator_EachPanic(t *testing.T) {
+	stop := errors.New("stop")
+	rows := newCloseCounter(3)
+	rows.err = errors.New("error close")
+	it, _ := newIterator[User](context.Background(), rows)
+	err := it.Each(func(u *User) error {
+		return stop
+	})
+	if !errors.Is(err, stop) || !errors.Is(err, rows.err) {
+		t.Errorf("expected  %v and %v, actual %v", stop, rows.err, err)
+	}
+
+	rows = newCloseCounter(3)
+	it, _ = newIterator[User](context.Background(), rows)
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("expected panic of fn to propagate")
+			}
+		}()
+		it.Each(func(u *User) error {
+			panic("fn")
+		})
+	}()
+	if *rows.closed != 1 {
+		t.Errorf("expected rows closed once, actual %d", *rows.closed)
+	}
+}
+
+func TestSQLRepository_EachUser(t *testing.T) {
+	tests := []struct {
+		m   Mock
+		err error
+	}{
+		{m: Mock{nextCnt: 1}, err: nil},
+		{m: Mock{errNo: 1}, err: errors.New("error query")},
+		{m: Mock{nextCnt: 1, errNo: 2}, err: errors.New("error scan")},
+		{m: Mock{errNo: 12}, err: errors.New("error rows")},
+	}
+	for i, test := range tests {
+		Gi = 0
+		test.m.closed = new(int)
+		r := newSQLRecorder(test.m)
+		repo := SQLRepository{SQLhandler: r}
+		users := []*User{}
+		err := repo.EachUser(context.Background(), func(u *User) error {
+			users = append(users, u)
+			return nil
+		})
+		if (err == nil) != (test.err == nil) || err != nil && err.Error() != test.err.Error() {
+			t.Errorf("%d, expected  %v, actual %v", i, test.err, err)
+		}
+		if test.err == nil && len(users) != 2 {
+			t.Errorf("%d, expected 2 users, actual %d", i, len(users))
+		}
+		if test.m.errNo != 1 && *test.m.closed != 1 {
+			t.Errorf("%d, expected rows closed once, actual %d", i, *test.m.closed)
+		}
+		query := "SELECT " + userColumns + " FROM users WHERE deleted_at IS NULL ORDER BY id ASC "
+		if (*r.queries)[0] != query {
+			t.Errorf("%d, expected  %v, actual %v", i, query, (*r.queries)[0])
+		}
+	}
+
+	m := Mock{nextCnt: 1, closed: new(int)}
+	repo := SQLRepository{SQLhandler: m}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("expected panic of fn to propagate")
+			}
+		}()
+		repo.EachUser(context.Background(), func(u *User) error {
+			panic("fn")
+		})
+	}()
+	if *m.closed != 1 {
+		t.Errorf("expected rows closed once, actual %d", *m.closed)
+	}
+}
@@ -0,0 +1,61 @@
+package interfaces
+
+import (
+	"errors"
+
+	"github.com/nakamura244/databasesql/interfaces/query"
+)
+
+// LockMode is how a read in a transaction locks the rows it selects.
+// Combine a lock with at most one of SkipLocked and NoWait, e.g. ForUpdate|NoWait.
+type LockMode int
+
+const (
+	// ForUpdate is SELECT ... FOR UPDATE, an exclusive lock
+	ForUpdate LockMode = 1 << iota
+	// ForShare is SELECT ... LOCK IN SHARE MODE, FOR SHARE in Postgres, a shared lock
+	ForShare
+	// SkipLocked leaves out the rows locked by others instead of waiting (MySQL 8.0, Postgres)
+	SkipLocked
+	// NoWait fails with ErrLockNotAvailable instead of waiting (MySQL 8.0, Postgres)
+	NoWait
+)
+
+var errLockMode = errors.New("interfaces: invalid lock mode")
+
+// validate returns an error unless m is one lock with at most one modifier
+func (m LockMode) validate() error {
+	lock := m & (ForUpdate | ForShare)
+	if lock != ForUpdate && lock != ForShare {
+		return errLockMode
+	}
+	if m&SkipLocked != 0 && m&NoWait != 0 {
+		return errLockMode
+	}
+	if m&^(ForUpdate|ForShare|SkipLocked|NoWait) != 0 {
+		return errLockMode
+	}
+	return nil
+}
+
+// clause is the locking clause of m at the end of SELECT in d
+func (m LockMode) clause(d query.Dialect) string {
+	var s string
+	switch {
+	case m&ForUpdate != 0:
+		s = `FOR UPDATE`
+	case m&(SkipLocked|NoWait) != 0 || d == query.Postgres:
+		// LOCK IN SHARE MODE takes no modifier
+		s = `FOR SHARE`
+	default:
+		// understood by MySQL 5.7 as well
+		s = `LOCK IN SHARE MODE`
+	}
+	if m&SkipLocked != 0 {
+		s += ` SKIP LOCKED`
+	}
+	if m&NoWait != 0 {
+		s += ` NOWAIT`
+	}
+	return s
+}
@@ -0,0 +1,119 @@
+package interfaces
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/nakamura244/databasesql/interfaces/query"
+)
+
+// lockedTx is Mock whose queries fail with err, like a row locked by another tx
+type lockedTx struct {
+	Mock
+	err error
+}
+
+func (tx lockedTx) QueryContext(context.Context, string, ...interface{}) (Rows, error) {
+	return nil, tx.err
+}
+
+func TestLockMode_Clause(t *testing.T) {
+	tests := []struct {
+		mode     LockMode
+		clause   string
+		postgres string
+		err      error
+	}{
+		{mode: ForUpdate, clause: "FOR UPDATE", postgres: "FOR UPDATE"},
+		{mode: ForUpdate | SkipLocked, clause: "FOR UPDATE SKIP LOCKED", postgres: "FOR UPDATE SKIP LOCKED"},
+		{mode: ForUpdate | NoWait, clause: "FOR UPDATE NOWAIT", postgres: "FOR UPDATE NOWAIT"},
+		{mode: ForShare, clause: "LOCK IN SHARE MODE", postgres: "FOR SHARE"},
+		{mode: ForShare | SkipLocked, clause: "FOR SHARE SKIP LOCKED", postgres: "FOR SHARE SKIP LOCKED"},
+		{mode: ForShare | NoWait, clause: "FOR SHARE NOWAIT", postgres: "FOR SHARE NOWAIT"},
+		{mode: 0, err: errLockMode},
+		{mode: NoWait, err: errLockMode},
+		{mode: ForUpdate | ForShare, err: errLockMode},
+		{mode: ForUpdate | SkipLocked | NoWait, err: errLockMode},
+		{mode: LockMode(1 << 8), err: errLockMode},
+	}
+	for i, test := range tests {
+		if err := test.mode.validate(); err != test.err {
+			t.Errorf("%d, expected  %v, actual %v", i, test.err, err)
+			continue
+		}
+		if test.err == nil && test.mode.clause(query.MySQL) != test.clause {
+			t.Errorf("%d, expected  %v, actual %v", i, test.clause, test.mode.clause(query.MySQL))
+		}
+		if test.err == nil && test.mode.clause(query.Postgres) != test.postgres {
+			t.Errorf("%d, expected  %v, actual %v", i, test.postgres, test.mode.clause(query.Postgres))
+		}
+	}
+}
+
+func TestSQLRepository_FindUserByIDForUpdate(t *testing.T) {
+	tests := []struct {
+		mode  LockMode
+		query string
+	}{
+		{mode: ForUpdate, query: "SELECT " + userColumns + " FROM users WHERE deleted_at IS NULL AND id = ? FOR UPDATE "},
+		{mode: ForShare | NoWait, query: "SELECT " + userColumns + " FROM users WHERE deleted_at IS NULL AND id = ? FOR SHARE NOWAIT "},
+	}
+	for i, test := range tests {
+		tx := newSQLRecorder(Mock{})
+		// the read goes through tx, never the handler
+		repo := &SQLRepository{SQLhandler: Mock{errNo: 1}}
+		u, err := repo.FindUserByIDForUpdate(context.Background(), tx, 2, test.mode)
+		if err != nil {
+			t.Fatalf("%d, expected  %v, actual %v", i, nil, err)
+		}
+		if !reflect.DeepEqual(u, mockUser()) {
+			t.Errorf("%d, expected %+v, got %+v", i, mockUser(), u)
+		}
+		if (*tx.queries)[0] != test.query {
+			t.Errorf("%d, expected  %v, actual %v", i, test.query, (*tx.queries)[0])
+		}
+	}
+}
+
+func TestSQLRepository_FindUserByIDForUpdatePostgres(t *testing.T) {
+	tests := []struct {
+		mode  LockMode
+		query string
+	}{
+		{mode: ForShare, query: "SELECT " + userColumns + " FROM users WHERE deleted_at IS NULL AND id = $1 FOR SHARE "},
+		{mode: ForUpdate | SkipLocked, query: "SELECT " + userColumns + " FROM users WHERE deleted_at IS NULL AND id = $1 FOR UPDATE SKIP LOCKED "},
+	}
+	for i, test := range tests {
+		r := newPgRecorder()
+		repo := &SQLRepository{SQLhandler: r}
+		if _, err := repo.FindUserByIDForUpdate(context.Background(), r, 2, test.mode); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%d, expected  %v, actual %v", i, ErrNotFound, err)
+		}
+		if (*r.queries)[0] != test.query {
+			t.Errorf("%d, expected  %v, actual %v", i, test.query, (*r.queries)[0])
+		}
+	}
+}
+
+func TestSQLRepository_FindUserByIDForUpdateError(t *testing.T) {
+	lockErr := &DBError{Kind: ErrLockNotAvailable, Err: errors.New("NOWAIT is set")}
+	tests := []struct {
+		tx   Tx
+		mode LockMode
+		err  error
+	}{
+		{tx: Mock{}, mode: ForUpdate | SkipLocked | NoWait, err: errLockMode},
+		{tx: Mock{errNo: 4}, mode: ForUpdate | SkipLocked, err: ErrNotFound},
+		{tx: lockedTx{err: lockErr}, mode: ForUpdate | NoWait, err: ErrLockNotAvailable},
+		{tx: lockedTx{err: &DBError{Kind: ErrLockTimeout}}, mode: ForUpdate, err: ErrTimeout},
+	}
+	for i, test := range tests {
+		repo := &SQLRepository{SQLhandler: Mock{}}
+		_, err := repo.FindUserByIDForUpdate(context.Background(), test.tx, 2, test.mode)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%d, expected  %v, actual %v", i, test.err, err)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuilder_ToSQL(t *testing.T) {
+	// an empty postgres is a statement Postgres does not have
+	tests := []struct {
+		b        Builder
+		mysql    string
+		postgres string
+		args     []interface{}
+	}{
+		{
+			b:        Select("id", "email").From("users").Where(IsNull("deleted_at"), Eq("id", 3)),
+			mysql:    "SELECT id, email FROM users WHERE deleted_at IS NULL AND id = ? ",
+			postgres: "SELECT id, email FROM users WHERE deleted_at IS NULL AND id = $1 ",
+			args:     []interface{}{3},
+		},
+		{
+			b: Select("u.id", "o.*").From("users u").
+				Join("orders o", Expr("o.user_id = u.id AND o.status = ?", 1)).
+				Where(Or(Eq("u.email", "a"), In("u.id", []int{1, 2}))).
+				OrderBy("u.id DESC", "o.id").Limit(10).Offset(20).Suffix("FOR UPDATE"),
+			mysql: "SELECT u.id, o.* FROM users u JOIN orders o ON o.user_id = u.id AND o.status = ? " +
+				"WHERE u.email = ? OR u.id IN (?, ?) ORDER BY u.id DESC, o.id LIMIT ? OFFSET ? FOR UPDATE ",
+			postgres: "SELECT u.id, o.* FROM users u JOIN orders o ON o.user_id = u.id AND o.status = $1 " +
+				"WHERE u.email = $2 OR u.id IN ($3, $4) ORDER BY u.id DESC, o.id LIMIT $5 OFFSET $6 FOR UPDATE ",
+			args: []interface{}{1, "a", 1, 2, 10, 20},
+		},
+		{
+			b:        Select("1").From("users").LeftJoin("orders", Expr("orders.user_id = users.id")).Offset(5),
+			mysql:    "SELECT 1 FROM users LEFT JOIN orders ON orders.user_id = users.id ",
+			postgres: "SELECT 1 FROM users LEFT JOIN orders ON orders.user_id = users.id ",
+			args:     []interface{}{},
+		},
+		{
+			b:        Insert("users").Columns("email", "name").Values("a", "b").Values("c", nil),
+			mysql:    "INSERT INTO users ( email, name ) VALUES (?, ?), (?, ?) ",
+			postgres: "INSERT INTO users ( email, name ) VALUES ($1, $2), ($3, $4) ",
+			args:     []interface{}{"a", "b", "c", nil},
+		},
+		{
+			b:        Insert("users").Columns("email").Values("a").Suffix("RETURNING id"),
+			mysql:    "INSERT INTO users ( email ) VALUES (?) RETURNING id ",
+			postgres: "INSERT INTO users ( email ) VALUES ($1) RETURNING id ",
+			args:     []interface{}{"a"},
+		},
+		{
+			b: Update("users").Set("email", "a").SetExpr("version", "version + ?", 1).
+				Where(Eq("id", 3), Eq("version", 2)).Limit(1),
+			mysql:    "UPDATE users SET email = ?, version = version + ? WHERE id = ? AND version = ? LIMIT ? ",
+			postgres: "",
+			args:     []interface{}{"a", 1, 3, 2, 1},
+		},
+		{
+			b:        Update("users").Set("email", "a").Where(Eq("id", 3)),
+			mysql:    "UPDATE users SET email = ? WHERE id = ? ",
+			postgres: "UPDATE users SET email = $1 WHERE id = $2 ",
+			args:     []interface{}{"a", 3},
+		},
+		{
+			b:        Delete("users").Where(IsNotNull("deleted_at"), Lt("deleted_at", "t")).Limit(100),
+			mysql:    "DELETE FROM users WHERE deleted_at IS NOT NULL AND deleted_at < ? LIMIT ? ",
+			postgres: "",
+			args:     []interface{}{"t", 100},
+		},
+		{
+			b:        Delete("users"),
+			mysql:    "DELETE FROM users ",
+			postgres: "DELETE FROM users ",
+			args:     []interface{}{},
+		},
+	}
+	for i, test := range tests {
+		for d, expected := range map[Dialect]string{MySQL: test.mysql, Postgres: test.postgres} {
+			sqlstr, args, err := test.b.ToSQL(d)
+			if expected == "" {
+				if err == nil {
+					t.Errorf("%d, expected an error for %v, actual %v", i, d, sqlstr)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("%d, expected  %v, actual %v", i, nil, err)
+			}
+			if sqlstr != expected {
+				t.Errorf("%d, expected  %v, actual %v", i, expected, sqlstr)
+			}
+			if !reflect.DeepEqual(args, test.args) {
+				t.Errorf("%d, expected  %v, actual %v", i, test.args, args)
+			}
+		}
+	}
+}
+
+func TestBuilder_ToSQLError(t *testing.T) {
+	tests := []Builder{
+		Select(),
+		Select("id").From("users; DROP TABLE users"),
+		Select("id, email").From("users"),
+		Select("id").From("users").OrderBy("id; DROP TABLE users"),
+		Select("id").From("users").Where(Eq("1=1 OR id", 1)),
+		Select("id").From("users").Join("orders", Eq("user id", 1)),
+		Select("id").From("users").Join("orders", Cond{}),
+		Select("id").From("users").LeftJoin("orders", And()),
+		Select("id").From("users").Suffix("LIMIT ?"),
+		Insert("users").Columns("email"),
+		Insert("users").Columns("email", "name").Values("a"),
+		Insert("users").Values("a"),
+		Insert("users(email)").Columns("email").Values("a"),
+		Update("users"),
+		Update("users").Set("email = email, name", "a"),
+		Update("users").SetExpr("version", "version + ?"),
+		Delete("users").Where(In("id;", 1)),
+		Delete("").Where(Eq("id", 1)),
+	}
+	for i, test := range tests {
+		if _, _, err := test.ToSQL(MySQL); err == nil {
+			t.Errorf("%d, expected an error, actual %v", i, err)
+		}
+	}
+}
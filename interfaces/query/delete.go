@@ -0,0 +1,57 @@
+package query
+
+import "fmt"
+
+// DeleteBuilder builds a DELETE statement
+type DeleteBuilder struct {
+	table string
+	where []Cond
+	limit int
+	err   error
+}
+
+// Delete starts DELETE FROM table
+func Delete(table string) *DeleteBuilder {
+	b := &DeleteBuilder{table: table}
+	if err := checkIdent(table); err != nil {
+		b.err = err
+	}
+	return b
+}
+
+// Where adds conds, all of which the rows must meet
+func (b *DeleteBuilder) Where(conds ...Cond) *DeleteBuilder {
+	b.where = append(b.where, conds...)
+	return b
+}
+
+// Limit is the most rows to delete, no limit when 0. PostgreSQL has no such clause,
+// ToSQL fails for Postgres with a Limit.
+func (b *DeleteBuilder) Limit(n int) *DeleteBuilder {
+	b.limit = n
+	return b
+}
+
+// ToSQL is the statement for d and its args
+func (b *DeleteBuilder) ToSQL(d Dialect) (string, []interface{}, error) {
+	if b.err != nil {
+		return "", nil, b.err
+	}
+	sqlstr := `DELETE FROM ` + b.table + ` `
+	where, args, err := whereSQL(b.where)
+	if err != nil {
+		return "", nil, err
+	}
+	sqlstr += where
+	if args == nil {
+		args = []interface{}{}
+	}
+	if b.limit > 0 {
+		if d == Postgres {
+			return "", nil, fmt.Errorf("query: Postgres has no LIMIT in DELETE FROM %s", b.table)
+		}
+		sqlstr += `LIMIT ? `
+		args = append(args, b.limit)
+	}
+	return Rebind(d, sqlstr), args, nil
+}
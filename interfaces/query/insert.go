@@ -0,0 +1,85 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+)
+
+// InsertBuilder builds an INSERT statement of one or more rows
+type InsertBuilder struct {
+	table   string
+	columns []string
+	rows    [][]interface{}
+	suffix  Cond
+	err     error
+}
+
+// Insert starts INSERT INTO table
+func Insert(table string) *InsertBuilder {
+	b := &InsertBuilder{table: table}
+	if err := checkIdent(table); err != nil {
+		b.fail(err)
+	}
+	return b
+}
+
+// Columns are the columns the rows have values for
+func (b *InsertBuilder) Columns(cols ...string) *InsertBuilder {
+	for _, c := range cols {
+		if err := checkIdent(c); err != nil {
+			b.fail(err)
+		}
+	}
+	b.columns = cols
+	return b
+}
+
+// Values adds a row with a value for each of the columns
+func (b *InsertBuilder) Values(vs ...interface{}) *InsertBuilder {
+	b.rows = append(b.rows, vs)
+	return b
+}
+
+// Suffix is SQL put at the end as is, e.g. ON DUPLICATE KEY UPDATE ...
+func (b *InsertBuilder) Suffix(sql string, args ...interface{}) *InsertBuilder {
+	b.suffix = Expr(sql, args...)
+	return b
+}
+
+func (b *InsertBuilder) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+// ToSQL is the statement for d and its args
+func (b *InsertBuilder) ToSQL(d Dialect) (string, []interface{}, error) {
+	if b.err != nil {
+		return "", nil, b.err
+	}
+	if len(b.columns) == 0 {
+		return "", nil, errNoColumns
+	}
+	if len(b.rows) == 0 {
+		return "", nil, fmt.Errorf("query: no values to insert into %s", b.table)
+	}
+	row := `(` + placeholders(len(b.columns)) + `)`
+	args := make([]interface{}, 0, len(b.rows)*len(b.columns))
+	for i, r := range b.rows {
+		if len(r) != len(b.columns) {
+			return "", nil, fmt.Errorf("query: row %d has %d values for %d columns", i, len(r), len(b.columns))
+		}
+		args = append(args, r...)
+	}
+	sqlstr := `INSERT INTO ` + b.table + ` ( ` +
+		strings.Join(b.columns, ", ") + ` ` +
+		`) VALUES ` + strings.Repeat(row+", ", len(b.rows)-1) + row + ` `
+	if b.suffix.err != nil {
+		return "", nil, b.suffix.err
+	}
+	if b.suffix.sql != "" {
+		sqlstr += b.suffix.sql + ` `
+		args = append(args, b.suffix.args...)
+	}
+	return Rebind(d, sqlstr), args, nil
+}
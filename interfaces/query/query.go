@@ -0,0 +1,310 @@
+// Package query builds SQL statements with their args.
+// Values always become placeholders and args, and table and column names must be
+// plain identifiers, so only Expr and Suffix put caller written SQL in a statement.
+package query
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Dialect is how placeholders are written
+type Dialect int
+
+const (
+	// MySQL numbers nothing, every placeholder is ?
+	MySQL Dialect = iota
+	// Postgres numbers placeholders $1, $2, ...
+	Postgres
+)
+
+// Builder is a statement rendered for a Dialect
+type Builder interface {
+	ToSQL(d Dialect) (string, []interface{}, error)
+}
+
+var errNoColumns = errors.New("query: no columns")
+
+var (
+	identRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+	tableRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?( (AS |as )?[A-Za-z_][A-Za-z0-9_]*)?$`)
+	orderRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?( (ASC|DESC|asc|desc))?$`)
+)
+
+// checkIdent returns an error unless s is a column or a table qualified column
+func checkIdent(s string) error {
+	if !identRe.MatchString(s) {
+		return fmt.Errorf("query: invalid identifier %q", s)
+	}
+	return nil
+}
+
+// checkTable is checkIdent that also takes an alias, e.g. "users u"
+func checkTable(s string) error {
+	if !tableRe.MatchString(s) {
+		return fmt.Errorf("query: invalid table %q", s)
+	}
+	return nil
+}
+
+// checkColumn is checkIdent that also takes *, t.* and integer literals
+func checkColumn(s string) error {
+	if s == "*" || strings.HasSuffix(s, ".*") && identRe.MatchString(strings.TrimSuffix(s, ".*")) {
+		return nil
+	}
+	if _, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return nil
+	}
+	return checkIdent(s)
+}
+
+// checkOrder returns an error unless s is a column optionally followed by ASC or DESC
+func checkOrder(s string) error {
+	if !orderRe.MatchString(s) {
+		return fmt.Errorf("query: invalid order %q", s)
+	}
+	return nil
+}
+
+// Cond is a condition of WHERE or ON with its args
+type Cond struct {
+	sql  string
+	args []interface{}
+	// op is AND or OR when the condition joins more than one
+	op  string
+	err error
+}
+
+// Expr is a condition written in SQL with ? placeholders for args.
+// It is put in the statement as is, so put it in parentheses when it has OR.
+func Expr(sql string, args ...interface{}) Cond {
+	if n := countPlaceholders(sql); n != len(args) {
+		return Cond{err: fmt.Errorf("query: %q has %d placeholders for %d args", sql, n, len(args))}
+	}
+	return Cond{sql: sql, args: args}
+}
+
+// Eq is col = v
+func Eq(col string, v interface{}) Cond { return compare(col, "=", v) }
+
+// Ne is col <> v
+func Ne(col string, v interface{}) Cond { return compare(col, "<>", v) }
+
+// Lt is col < v
+func Lt(col string, v interface{}) Cond { return compare(col, "<", v) }
+
+// Le is col <= v
+func Le(col string, v interface{}) Cond { return compare(col, "<=", v) }
+
+// Gt is col > v
+func Gt(col string, v interface{}) Cond { return compare(col, ">", v) }
+
+// Ge is col >= v
+func Ge(col string, v interface{}) Cond { return compare(col, ">=", v) }
+
+// Like is col LIKE pattern. Escape the wildcards of a value that is not a pattern.
+func Like(col string, pattern string) Cond { return compare(col, "LIKE", pattern) }
+
+func compare(col, op string, v interface{}) Cond {
+	if err := checkIdent(col); err != nil {
+		return Cond{err: err}
+	}
+	return Cond{sql: col + ` ` + op + ` ?`, args: []interface{}{v}}
+}
+
+// IsNull is col IS NULL
+func IsNull(col string) Cond {
+	if err := checkIdent(col); err != nil {
+		return Cond{err: err}
+	}
+	return Cond{sql: col + ` IS NULL`}
+}
+
+// IsNotNull is col IS NOT NULL
+func IsNotNull(col string) Cond {
+	if err := checkIdent(col); err != nil {
+		return Cond{err: err}
+	}
+	return Cond{sql: col + ` IS NOT NULL`}
+}
+
+// In is col IN (vs...). A single slice in vs is expanded, []byte excepted.
+// Without values it is false.
+func In(col string, vs ...interface{}) Cond {
+	if err := checkIdent(col); err != nil {
+		return Cond{err: err}
+	}
+	vs = expand(vs)
+	if len(vs) == 0 {
+		return Cond{sql: `1 = 0`}
+	}
+	return Cond{sql: col + ` IN (` + placeholders(len(vs)) + `)`, args: vs}
+}
+
+// And is conds joined with AND. Empty conditions are left out.
+func And(conds ...Cond) Cond { return join("AND", conds) }
+
+// Or is conds joined with OR. Empty conditions are left out.
+func Or(conds ...Cond) Cond { return join("OR", conds) }
+
+func join(op string, conds []Cond) Cond {
+	cs := []Cond{}
+	for _, c := range conds {
+		if c.err != nil {
+			return c
+		}
+		if c.sql != "" {
+			cs = append(cs, c)
+		}
+	}
+	switch len(cs) {
+	case 0:
+		return Cond{}
+	case 1:
+		return cs[0]
+	}
+	parts := make([]string, len(cs))
+	args := []interface{}{}
+	for i, c := range cs {
+		parts[i] = c.sql
+		if c.op != "" && c.op != op {
+			parts[i] = `(` + c.sql + `)`
+		}
+		args = append(args, c.args...)
+	}
+	return Cond{sql: strings.Join(parts, ` `+op+` `), args: args, op: op}
+}
+
+// whereSQL is the WHERE clause of conds joined with AND, empty when there is none
+func whereSQL(conds []Cond) (string, []interface{}, error) {
+	c := And(conds...)
+	if c.err != nil {
+		return "", nil, c.err
+	}
+	if c.sql == "" {
+		return "", nil, nil
+	}
+	return `WHERE ` + c.sql + ` `, c.args, nil
+}
+
+// expand is the elements of vs when vs is one slice
+func expand(vs []interface{}) []interface{} {
+	if len(vs) != 1 {
+		return vs
+	}
+	switch s := vs[0].(type) {
+	case []interface{}:
+		return s
+	case []byte:
+		return vs
+	}
+	rv := reflect.ValueOf(vs[0])
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return vs
+	}
+	res := make([]interface{}, rv.Len())
+	for i := range res {
+		res[i] = rv.Index(i).Interface()
+	}
+	return res
+}
+
+// placeholders is n comma separated ?
+func placeholders(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strings.Repeat("?, ", n-1) + "?"
+}
+
+// countPlaceholders is the number of ? in sqlstr outside quotes and comments
+func countPlaceholders(sqlstr string) int {
+	n := 0
+	walk(sqlstr, func(i int) { n++ })
+	return n
+}
+
+// Rebind rewrites the ? placeholders of sqlstr for d.
+// A ? in a quoted string, a quoted identifier or a comment is kept.
+func Rebind(d Dialect, sqlstr string) string {
+	if d != Postgres {
+		return sqlstr
+	}
+	var b strings.Builder
+	last, n := 0, 0
+	walk(sqlstr, func(i int) {
+		n++
+		b.WriteString(sqlstr[last:i])
+		b.WriteString(`$` + strconv.Itoa(n))
+		last = i + 1
+	})
+	b.WriteString(sqlstr[last:])
+	return b.String()
+}
+
+// HasKeyword is whether sqlstr has word, in any case and not as part of
+// a longer name, outside quotes and comments, e.g. HasKeyword(s, "RETURNING")
+func HasKeyword(sqlstr, word string) bool {
+	found := false
+	code(sqlstr, func(i int) {
+		end := i + len(word)
+		if found || end > len(sqlstr) || !strings.EqualFold(sqlstr[i:end], word) {
+			return
+		}
+		found = (i == 0 || !isWordByte(sqlstr[i-1])) && (end == len(sqlstr) || !isWordByte(sqlstr[end]))
+	})
+	return found
+}
+
+// isWordByte is whether c can be in a keyword or a name
+func isWordByte(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+// walk calls fn with the offset of every ? in sqlstr outside quotes and comments
+func walk(sqlstr string, fn func(int)) {
+	code(sqlstr, func(i int) {
+		if sqlstr[i] == '?' {
+			fn(i)
+		}
+	})
+}
+
+// code calls fn with the offset of every byte of sqlstr outside quotes and comments
+func code(sqlstr string, fn func(int)) {
+	for i := 0; i < len(sqlstr); i++ {
+		switch c := sqlstr[i]; c {
+		case '\'', '"', '`':
+			// a doubled quote is an escaped one, which this skips as two literals
+			for i++; i < len(sqlstr) && sqlstr[i] != c; i++ {
+				if sqlstr[i] == '\\' && c == '\'' {
+					i++
+				}
+			}
+		case '-':
+			if !strings.HasPrefix(sqlstr[i:], "--") {
+				fn(i)
+				continue
+			}
+			for i < len(sqlstr) && sqlstr[i] != '\n' {
+				i++
+			}
+		case '/':
+			if !strings.HasPrefix(sqlstr[i:], "/*") {
+				fn(i)
+				continue
+			}
+			end := strings.Index(sqlstr[i+2:], "*/")
+			if end < 0 {
+				return
+			}
+			i += end + 3
+		default:
+			fn(i)
+		}
+	}
+}
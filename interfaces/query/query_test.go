@@ -0,0 +1,102 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCond(t *testing.T) {
+	tests := []struct {
+		cond Cond
+		sql  string
+		args []interface{}
+	}{
+		{cond: Eq("id", 1), sql: "id = ?", args: []interface{}{1}},
+		{cond: Ne("u.id", 1), sql: "u.id <> ?", args: []interface{}{1}},
+		{cond: Le("price", 5), sql: "price <= ?", args: []interface{}{5}},
+		{cond: Like("email", "a%"), sql: "email LIKE ?", args: []interface{}{"a%"}},
+		{cond: IsNull("deleted_at"), sql: "deleted_at IS NULL"},
+		{cond: In("id", 1, 2), sql: "id IN (?, ?)", args: []interface{}{1, 2}},
+		{cond: In("id", []uint{1, 2, 3}), sql: "id IN (?, ?, ?)", args: []interface{}{uint(1), uint(2), uint(3)}},
+		{cond: In("hash", []byte("ab")), sql: "hash IN (?)", args: []interface{}{[]byte("ab")}},
+		{cond: In("id", []int{}), sql: "1 = 0"},
+		{cond: And(Eq("a", 1), Cond{}, Eq("b", 2)), sql: "a = ? AND b = ?", args: []interface{}{1, 2}},
+		{cond: Or(Eq("a", 1)), sql: "a = ?", args: []interface{}{1}},
+		{cond: And(IsNull("d"), Or(Eq("a", 1), Eq("b", 2))), sql: "d IS NULL AND (a = ? OR b = ?)", args: []interface{}{1, 2}},
+		{cond: Or(And(Eq("a", 1), Eq("b", 2)), Eq("c", 3)), sql: "(a = ? AND b = ?) OR c = ?", args: []interface{}{1, 2, 3}},
+		{cond: And(And(Eq("a", 1), Eq("b", 2)), Eq("c", 3)), sql: "a = ? AND b = ? AND c = ?", args: []interface{}{1, 2, 3}},
+		{cond: Expr("a = ? OR b = '?'", 1), sql: "a = ? OR b = '?'", args: []interface{}{1}},
+	}
+	for i, test := range tests {
+		if test.cond.err != nil {
+			t.Errorf("%d, expected  %v, actual %v", i, nil, test.cond.err)
+			continue
+		}
+		if test.cond.sql != test.sql {
+			t.Errorf("%d, expected  %v, actual %v", i, test.sql, test.cond.sql)
+		}
+		if len(test.cond.args) != 0 || len(test.args) != 0 {
+			if !reflect.DeepEqual(test.cond.args, test.args) {
+				t.Errorf("%d, expected  %v, actual %v", i, test.args, test.cond.args)
+			}
+		}
+	}
+}
+
+func TestCond_Error(t *testing.T) {
+	tests := []Cond{
+		Eq("id; DROP TABLE users", 1),
+		IsNull("a b"),
+		In("`id`", 1),
+		Expr("a = ? AND b = ?", 1),
+		Expr("a = ?", 1, 2),
+		And(Eq("a", 1), Eq("1a", 2)),
+	}
+	for i, test := range tests {
+		if test.err == nil {
+			t.Errorf("%d, expected an error, actual %v", i, test.sql)
+		}
+	}
+}
+
+func TestHasKeyword(t *testing.T) {
+	tests := []struct {
+		sql string
+		r   bool
+	}{
+		{sql: "INSERT INTO t ( a ) VALUES (?) RETURNING id", r: true},
+		{sql: "delete from t where id = ? returning *;", r: true},
+		{sql: "UPDATE t SET note = 'returning customer' WHERE id = ?", r: false},
+		{sql: "UPDATE t SET \"returning\" = ?, `Returning` = ?", r: false},
+		{sql: "UPDATE t SET a = ? -- RETURNING id\nWHERE id = ?", r: false},
+		{sql: "UPDATE t SET a = ? /* RETURNING id */ WHERE id = ?", r: false},
+		{sql: "UPDATE t SET returning_id = ?, not_returning = ?", r: false},
+		{sql: "RETURNING", r: true},
+	}
+	for i, test := range tests {
+		if r := HasKeyword(test.sql, "RETURNING"); r != test.r {
+			t.Errorf("%d, expected  %v, actual %v", i, test.r, r)
+		}
+	}
+}
+
+func TestRebind(t *testing.T) {
+	tests := []struct {
+		sql      string
+		postgres string
+	}{
+		{sql: "SELECT * FROM t WHERE a = ? AND b = ? ", postgres: "SELECT * FROM t WHERE a = $1 AND b = $2 "},
+		{sql: "SELECT '?', \"?\", `?` FROM t WHERE a = ?", postgres: "SELECT '?', \"?\", `?` FROM t WHERE a = $1"},
+		{sql: "SELECT 'it''s ?', 'a\\'?' FROM t WHERE a = ?", postgres: "SELECT 'it''s ?', 'a\\'?' FROM t WHERE a = $1"},
+		{sql: "SELECT 1 -- why?\nFROM t WHERE a = ? /* or ? */ AND b = ?", postgres: "SELECT 1 -- why?\nFROM t WHERE a = $1 /* or ? */ AND b = $2"},
+		{sql: "SELECT 1 - ? / ?", postgres: "SELECT 1 - $1 / $2"},
+	}
+	for i, test := range tests {
+		if r := Rebind(MySQL, test.sql); r != test.sql {
+			t.Errorf("%d, expected  %v, actual %v", i, test.sql, r)
+		}
+		if r := Rebind(Postgres, test.sql); r != test.postgres {
+			t.Errorf("%d, expected  %v, actual %v", i, test.postgres, r)
+		}
+	}
+}
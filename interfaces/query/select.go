@@ -0,0 +1,155 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SelectBuilder builds a SELECT statement
+type SelectBuilder struct {
+	columns []string
+	from    string
+	joins   []joinClause
+	where   []Cond
+	orderBy []string
+	limit   int
+	offset  int
+	suffix  Cond
+	err     error
+}
+
+type joinClause struct {
+	kind  string
+	table string
+	on    Cond
+}
+
+// Select starts SELECT cols
+func Select(cols ...string) *SelectBuilder {
+	b := &SelectBuilder{}
+	for _, c := range cols {
+		if err := checkColumn(c); err != nil {
+			b.fail(err)
+		}
+	}
+	b.columns = cols
+	return b
+}
+
+// From is the table to select from, optionally with an alias
+func (b *SelectBuilder) From(table string) *SelectBuilder {
+	if err := checkTable(table); err != nil {
+		b.fail(err)
+	}
+	b.from = table
+	return b
+}
+
+// Join adds JOIN table ON on. on must not be empty.
+func (b *SelectBuilder) Join(table string, on Cond) *SelectBuilder {
+	return b.join(`JOIN`, table, on)
+}
+
+// LeftJoin adds LEFT JOIN table ON on
+func (b *SelectBuilder) LeftJoin(table string, on Cond) *SelectBuilder {
+	return b.join(`LEFT JOIN`, table, on)
+}
+
+func (b *SelectBuilder) join(kind, table string, on Cond) *SelectBuilder {
+	if err := checkTable(table); err != nil {
+		b.fail(err)
+	}
+	b.joins = append(b.joins, joinClause{kind: kind, table: table, on: on})
+	return b
+}
+
+// Where adds conds, all of which the rows must meet
+func (b *SelectBuilder) Where(conds ...Cond) *SelectBuilder {
+	b.where = append(b.where, conds...)
+	return b
+}
+
+// OrderBy adds columns to sort by, each optionally followed by ASC or DESC
+func (b *SelectBuilder) OrderBy(exprs ...string) *SelectBuilder {
+	for _, e := range exprs {
+		if err := checkOrder(e); err != nil {
+			b.fail(err)
+		}
+	}
+	b.orderBy = append(b.orderBy, exprs...)
+	return b
+}
+
+// Limit is the most rows to select, no limit when 0
+func (b *SelectBuilder) Limit(n int) *SelectBuilder {
+	b.limit = n
+	return b
+}
+
+// Offset is the rows to skip. It needs a Limit.
+func (b *SelectBuilder) Offset(n int) *SelectBuilder {
+	b.offset = n
+	return b
+}
+
+// Suffix is SQL put at the end as is, e.g. FOR UPDATE
+func (b *SelectBuilder) Suffix(sql string, args ...interface{}) *SelectBuilder {
+	b.suffix = Expr(sql, args...)
+	return b
+}
+
+func (b *SelectBuilder) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+// ToSQL is the statement for d and its args
+func (b *SelectBuilder) ToSQL(d Dialect) (string, []interface{}, error) {
+	if b.err != nil {
+		return "", nil, b.err
+	}
+	if len(b.columns) == 0 {
+		return "", nil, errNoColumns
+	}
+	sqlstr := `SELECT ` + strings.Join(b.columns, ", ") + ` `
+	args := []interface{}{}
+	if b.from != "" {
+		sqlstr += `FROM ` + b.from + ` `
+	}
+	for _, j := range b.joins {
+		if j.on.err != nil {
+			return "", nil, j.on.err
+		}
+		if j.on.sql == "" {
+			return "", nil, fmt.Errorf("query: %s %s without ON", j.kind, j.table)
+		}
+		sqlstr += j.kind + ` ` + j.table + ` ON ` + j.on.sql + ` `
+		args = append(args, j.on.args...)
+	}
+	where, whereArgs, err := whereSQL(b.where)
+	if err != nil {
+		return "", nil, err
+	}
+	sqlstr += where
+	args = append(args, whereArgs...)
+	if len(b.orderBy) > 0 {
+		sqlstr += `ORDER BY ` + strings.Join(b.orderBy, ", ") + ` `
+	}
+	if b.limit > 0 {
+		sqlstr += `LIMIT ? `
+		args = append(args, b.limit)
+		if b.offset > 0 {
+			sqlstr += `OFFSET ? `
+			args = append(args, b.offset)
+		}
+	}
+	if b.suffix.err != nil {
+		return "", nil, b.suffix.err
+	}
+	if b.suffix.sql != "" {
+		sqlstr += b.suffix.sql + ` `
+		args = append(args, b.suffix.args...)
+	}
+	return Rebind(d, sqlstr), args, nil
+}
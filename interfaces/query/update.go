@@ -0,0 +1,89 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UpdateBuilder builds an UPDATE statement
+type UpdateBuilder struct {
+	table string
+	set   []string
+	args  []interface{}
+	where []Cond
+	limit int
+	err   error
+}
+
+// Update starts UPDATE table
+func Update(table string) *UpdateBuilder {
+	b := &UpdateBuilder{table: table}
+	if err := checkIdent(table); err != nil {
+		b.fail(err)
+	}
+	return b
+}
+
+// Set adds col = v
+func (b *UpdateBuilder) Set(col string, v interface{}) *UpdateBuilder {
+	return b.SetExpr(col, `?`, v)
+}
+
+// SetExpr adds col = expr, expr being SQL with ? placeholders for args, e.g. version + 1
+func (b *UpdateBuilder) SetExpr(col string, expr string, args ...interface{}) *UpdateBuilder {
+	if err := checkIdent(col); err != nil {
+		b.fail(err)
+	}
+	e := Expr(expr, args...)
+	if e.err != nil {
+		b.fail(e.err)
+	}
+	b.set = append(b.set, col+` = `+expr)
+	b.args = append(b.args, args...)
+	return b
+}
+
+// Where adds conds, all of which the rows must meet
+func (b *UpdateBuilder) Where(conds ...Cond) *UpdateBuilder {
+	b.where = append(b.where, conds...)
+	return b
+}
+
+// Limit is the most rows to update, no limit when 0. PostgreSQL has no such clause,
+// ToSQL fails for Postgres with a Limit.
+func (b *UpdateBuilder) Limit(n int) *UpdateBuilder {
+	b.limit = n
+	return b
+}
+
+func (b *UpdateBuilder) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+// ToSQL is the statement for d and its args
+func (b *UpdateBuilder) ToSQL(d Dialect) (string, []interface{}, error) {
+	if b.err != nil {
+		return "", nil, b.err
+	}
+	if len(b.set) == 0 {
+		return "", nil, fmt.Errorf("query: nothing to set in %s", b.table)
+	}
+	sqlstr := `UPDATE ` + b.table + ` SET ` + strings.Join(b.set, ", ") + ` `
+	args := append([]interface{}{}, b.args...)
+	where, whereArgs, err := whereSQL(b.where)
+	if err != nil {
+		return "", nil, err
+	}
+	sqlstr += where
+	args = append(args, whereArgs...)
+	if b.limit > 0 {
+		if d == Postgres {
+			return "", nil, fmt.Errorf("query: Postgres has no LIMIT in UPDATE %s", b.table)
+		}
+		sqlstr += `LIMIT ? `
+		args = append(args, b.limit)
+	}
+	return Rebind(d, sqlstr), args, nil
+}
@@ -0,0 +1,200 @@
+package interfaces
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+// structFields is the fields of a struct type, cached per type
+var structFields sync.Map // map[reflect.Type]*structInfo
+
+// structInfo is column name to field index path of a struct type
+type structInfo struct {
+	index   map[string][]int
+	columns []string // outer fields first, then in declaration order
+}
+
+// ScanStruct scans the current row of rows into the struct dest points to.
+// A column is matched to the field with the same `db` tag, or to the field
+// whose lower-cased name is the column when there is no tag. A tag of "-"
+// skips the field. Fields of embedded structs are matched as if they were
+// fields of dest, the shallower one wins when names collide.
+// Pointer fields are set to nil for NULL.
+// A column without a matching field is an error.
+func ScanStruct(rows Rows, dest interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("interfaces: ScanStruct dest must be a non-nil pointer to struct, got %T", dest)
+	}
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	targets, err := scanTargets(v.Elem(), cols, fieldsOf(v.Elem().Type()).index)
+	if err != nil {
+		return err
+	}
+	return rows.Scan(targets...)
+}
+
+// ScanAll scans the remaining rows and appends them to the slice dest points to.
+// The slice element is a struct or a pointer to struct, matched to columns
+// like ScanStruct. It does not close rows.
+func ScanAll(rows Rows, dest interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("interfaces: ScanAll dest must be a non-nil pointer to slice, got %T", dest)
+	}
+	slice := v.Elem()
+	elemType := slice.Type().Elem()
+	structType := elemType
+	if elemType.Kind() == reflect.Ptr {
+		structType = elemType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return fmt.Errorf("interfaces: ScanAll dest must be a slice of struct or pointer to struct, got %T", dest)
+	}
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	fields := fieldsOf(structType).index
+	for rows.Next() {
+		elem := reflect.New(structType)
+		targets, err := scanTargets(elem.Elem(), cols, fields)
+		if err != nil {
+			return err
+		}
+		if err := rows.Scan(targets...); err != nil {
+			return err
+		}
+		if elemType.Kind() == reflect.Ptr {
+			slice.Set(reflect.Append(slice, elem))
+		} else {
+			slice.Set(reflect.Append(slice, elem.Elem()))
+		}
+	}
+	return rows.Err()
+}
+
+// scanTargets is pointers to the fields of v in the order of cols
+func scanTargets(v reflect.Value, cols []string, fields map[string][]int) ([]interface{}, error) {
+	targets := make([]interface{}, len(cols))
+	for i, col := range cols {
+		index, ok := fields[col]
+		if !ok {
+			index, ok = fields[strings.ToLower(col)]
+		}
+		if !ok {
+			return nil, fmt.Errorf("interfaces: no field for column %q in %s", col, v.Type())
+		}
+		targets[i] = fieldByIndex(v, index).Addr().Interface()
+	}
+	return targets, nil
+}
+
+// fieldByIndex is like reflect.Value.FieldByIndex but allocates nil embedded pointers
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// fieldValue is the field at index of v, nil when an embedded pointer on the way is nil
+func fieldValue(v reflect.Value, index []int) interface{} {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return nil
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v.Interface()
+}
+
+// StructFields is the field index paths of struct type t by column name, matched
+// like ScanStruct matches them. The map is cached and shared, it must not be modified.
+func StructFields(t reflect.Type) map[string][]int {
+	return fieldsOf(t).index
+}
+
+// fieldsOf is the fields of struct type t
+func fieldsOf(t reflect.Type) *structInfo {
+	if f, ok := structFields.Load(t); ok {
+		return f.(*structInfo)
+	}
+	f, _ := structFields.LoadOrStore(t, collectFields(t))
+	return f.(*structInfo)
+}
+
+// collectFields walks t breadth first so that a field of an outer struct
+// hides a field with the same column of an embedded one
+func collectFields(t reflect.Type) *structInfo {
+	type level struct {
+		t     reflect.Type
+		index []int
+	}
+	info := &structInfo{index: map[string][]int{}}
+	visited := map[reflect.Type]bool{t: true}
+	queue := []level{{t: t}}
+	for len(queue) > 0 {
+		next := []level{}
+		for _, l := range queue {
+			for i := 0; i < l.t.NumField(); i++ {
+				f := l.t.Field(i)
+				tag := f.Tag.Get("db")
+				if tag == "-" {
+					continue
+				}
+				index := append(append([]int{}, l.index...), i)
+
+				ft := f.Type
+				if ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				if f.Anonymous && tag == "" && ft.Kind() == reflect.Struct && !reflect.PtrTo(ft).Implements(scannerType) {
+					// an unexported embedded pointer can not be allocated
+					if f.Type.Kind() == reflect.Ptr && !f.IsExported() {
+						continue
+					}
+					if !visited[ft] {
+						visited[ft] = true
+						next = append(next, level{t: ft, index: index})
+					}
+					continue
+				}
+				if !f.IsExported() {
+					continue
+				}
+
+				name := tag
+				if name == "" {
+					name = strings.ToLower(f.Name)
+				}
+				if _, ok := info.index[name]; ok {
+					// hidden by a shallower or earlier field
+					continue
+				}
+				info.index[name] = index
+				info.columns = append(info.columns, name)
+			}
+		}
+		queue = next
+	}
+	return info
+}
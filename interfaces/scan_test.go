@@ -0,0 +1,199 @@
+package interfaces
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// rowsMock is Rows returning fixed columns and values
+type rowsMock struct {
+	Rows
+	cols   []string
+	values [][]interface{}
+	cur    *int
+}
+
+func newRowsMock(cols []string, values ...[]interface{}) rowsMock {
+	return rowsMock{cols: cols, values: values, cur: new(int)}
+}
+
+func (r rowsMock) Columns() ([]string, error) {
+	return r.cols, nil
+}
+
+func (r rowsMock) Next() bool {
+	*r.cur++
+	return *r.cur <= len(r.values)
+}
+
+func (r rowsMock) Err() error {
+	return nil
+}
+
+func (r rowsMock) Scan(dest ...interface{}) error {
+	row := r.values[*r.cur-1]
+	if len(dest) != len(row) {
+		return errors.New("error scan")
+	}
+	for i, d := range dest {
+		if s, ok := d.(sql.Scanner); ok {
+			if err := s.Scan(row[i]); err != nil {
+				return err
+			}
+			continue
+		}
+		v := reflect.ValueOf(d).Elem()
+		switch {
+		case row[i] == nil:
+			v.Set(reflect.Zero(v.Type()))
+		case v.Kind() == reflect.Ptr:
+			p := reflect.New(v.Type().Elem())
+			p.Elem().Set(reflect.ValueOf(row[i]))
+			v.Set(p)
+		default:
+			v.Set(reflect.ValueOf(row[i]))
+		}
+	}
+	return nil
+}
+
+type scanBase struct {
+	ID        uint      `db:"id"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// scanAudit is skipped by scanUser, an unexported embedded pointer can not be allocated
+type scanAudit struct {
+	Note string `db:"note"`
+}
+
+type scanUser struct {
+	scanBase
+	*scanAudit
+	Email    string         `db:"email"`
+	Name     sql.NullString `db:"name"`
+	Nickname *string        `db:"nickname"`
+	Deleted  mysql.NullTime `db:"deleted_at"`
+	Age      int
+	Secret   string `db:"-"`
+}
+
+type ScanAudit struct {
+	ID   uint   `db:"id"` // hidden by scanUserWithAudit.ID
+	Note string `db:"note"`
+}
+
+type scanUserWithAudit struct {
+	ID uint `db:"id"`
+	*ScanAudit
+	Email string `db:"email"`
+}
+
+func TestScanStruct(t *testing.T) {
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	nick := "nick"
+	tests := []struct {
+		rows rowsMock
+		r    scanUser
+		err  error
+	}{
+		{
+			rows: newRowsMock(
+				[]string{"email", "id", "created_at", "name", "nickname", "deleted_at", "age"},
+				[]interface{}{"a@example.com", uint(3), created, "alice", "nick", created, 20},
+			),
+			r: scanUser{
+				scanBase: scanBase{ID: 3, CreatedAt: created},
+				Email:    "a@example.com",
+				Name:     sql.NullString{String: "alice", Valid: true},
+				Nickname: &nick,
+				Deleted:  mysql.NullTime{Time: created, Valid: true},
+				Age:      20,
+			},
+			err: nil,
+		},
+		{
+			rows: newRowsMock(
+				[]string{"ID", "name", "nickname", "deleted_at"},
+				[]interface{}{uint(4), nil, nil, nil},
+			),
+			r:   scanUser{scanBase: scanBase{ID: 4}},
+			err: nil,
+		},
+		{
+			rows: newRowsMock([]string{"note"}, []interface{}{"x"}),
+			err:  errors.New(`interfaces: no field for column "note" in interfaces.scanUser`),
+		},
+		{
+			rows: newRowsMock([]string{"secret"}, []interface{}{"x"}),
+			err:  errors.New(`interfaces: no field for column "secret" in interfaces.scanUser`),
+		},
+	}
+	for i, test := range tests {
+		test.rows.Next()
+		r := scanUser{}
+		err := ScanStruct(test.rows, &r)
+		if test.err != nil {
+			if err == nil || err.Error() != test.err.Error() {
+				t.Errorf("%d, expected  %v, actual %v", i, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%d, expected  %v, actual %v", i, nil, err)
+		}
+		if !reflect.DeepEqual(r, test.r) {
+			t.Errorf("%d, expected  %+v, actual %+v", i, test.r, r)
+		}
+	}
+
+	if err := ScanStruct(newRowsMock(nil), scanUser{}); err == nil {
+		t.Errorf("expected error for non pointer dest")
+	}
+}
+
+func TestScanStruct_EmbeddedPointer(t *testing.T) {
+	rows := newRowsMock([]string{"id", "note", "email"}, []interface{}{uint(1), "memo", "a@example.com"})
+	rows.Next()
+	r := scanUserWithAudit{}
+	if err := ScanStruct(rows, &r); err != nil {
+		t.Fatalf("expected  %v, actual %v", nil, err)
+	}
+	if r.ScanAudit == nil || r.Note != "memo" {
+		t.Errorf("expected embedded pointer to be allocated, actual %+v", r.ScanAudit)
+	}
+	if r.ID != 1 || r.ScanAudit.ID != 0 {
+		t.Errorf("expected outer id to win, actual %d %d", r.ID, r.ScanAudit.ID)
+	}
+}
+
+func TestScanAll(t *testing.T) {
+	cols := []string{"id", "email"}
+	rows := newRowsMock(cols, []interface{}{uint(1), "a@example.com"}, []interface{}{uint(2), "b@example.com"})
+	users := []*User{}
+	if err := ScanAll(rows, &users); err != nil {
+		t.Fatalf("expected  %v, actual %v", nil, err)
+	}
+	expected := []*User{{ID: 1, Email: "a@example.com"}, {ID: 2, Email: "b@example.com"}}
+	if !reflect.DeepEqual(users, expected) {
+		t.Errorf("expected  %+v, actual %+v", expected, users)
+	}
+
+	rows = newRowsMock(cols, []interface{}{uint(1), "a@example.com"})
+	values := []User{}
+	if err := ScanAll(rows, &values); err != nil {
+		t.Fatalf("expected  %v, actual %v", nil, err)
+	}
+	if len(values) != 1 || values[0].Email != "a@example.com" {
+		t.Errorf("expected 1 user, actual %+v", values)
+	}
+
+	if err := ScanAll(newRowsMock(cols), &[]int{}); err == nil {
+		t.Errorf("expected error for slice of int")
+	}
+}
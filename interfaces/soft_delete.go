@@ -0,0 +1,161 @@
+package interfaces
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nakamura244/databasesql/interfaces/query"
+)
+
+// purgeBatchSize is the most rows one DELETE of Purge removes,
+// to keep the locks it holds short
+const purgeBatchSize = 1000
+
+// deletedScope is which rows the finders see when the table has soft delete
+type deletedScope int
+
+const (
+	notDeleted deletedScope = iota
+	withDeleted
+	onlyDeleted
+)
+
+// QueryOption changes the rows a finder sees
+type QueryOption func(*queryOptions)
+
+type queryOptions struct {
+	deleted deletedScope
+}
+
+// WithDeleted makes a finder see soft deleted rows too
+func WithDeleted() QueryOption {
+	return func(o *queryOptions) {
+		o.deleted = withDeleted
+	}
+}
+
+// OnlyDeleted makes a finder see soft deleted rows only
+func OnlyDeleted() QueryOption {
+	return func(o *queryOptions) {
+		o.deleted = onlyDeleted
+	}
+}
+
+func newQueryOptions(opts []QueryOption) queryOptions {
+	o := queryOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// scope is the condition on Table.DeletedAt for opts, empty when there is none
+func (repo *Repository[T]) scope(opts []QueryOption) query.Cond {
+	if repo.Table.DeletedAt == "" {
+		return query.Cond{}
+	}
+	switch newQueryOptions(opts).deleted {
+	case withDeleted:
+		return query.Cond{}
+	case onlyDeleted:
+		return query.IsNotNull(repo.Table.DeletedAt)
+	default:
+		return query.IsNull(repo.Table.DeletedAt)
+	}
+}
+
+// notDeleted is the condition that skips soft deleted rows, empty without soft delete
+func (repo *Repository[T]) notDeleted() query.Cond {
+	if repo.Table.DeletedAt == "" {
+		return query.Cond{}
+	}
+	return query.IsNull(repo.Table.DeletedAt)
+}
+
+// softDelete sets Table.DeletedAt of the row with id. It returns ErrNotFound
+// when there is no such row or it is deleted already.
+func (repo *Repository[T]) softDelete(ctx context.Context, e execer, id interface{}) error {
+	return repo.setDeletedAt(ctx, e, id, now(), query.IsNull(repo.Table.DeletedAt))
+}
+
+// Restore clears Table.DeletedAt of the soft deleted row with id.
+// It returns ErrNotFound when there is no such row or it is not deleted.
+func (repo *Repository[T]) Restore(ctx context.Context, id interface{}) error {
+	if repo.Table.DeletedAt == "" {
+		return repo.errNoSoftDelete()
+	}
+	return repo.setDeletedAt(ctx, repo, id, nil, query.IsNotNull(repo.Table.DeletedAt))
+}
+
+func (repo *Repository[T]) setDeletedAt(ctx context.Context, e execer, id interface{}, deletedAt interface{}, cond query.Cond) error {
+	b := query.Update(repo.Table.Name).Set(repo.Table.DeletedAt, deletedAt)
+	if repo.Table.UpdatedAt != "" {
+		b.Set(repo.Table.UpdatedAt, now())
+	}
+	sqlstr, args, err := b.Where(query.Eq(repo.Table.PrimaryKey, id), cond).ToSQL(repo.dialect())
+	if err != nil {
+		return err
+	}
+	res, err := e.ExecuteContext(ctx, sqlstr, args...)
+	if err != nil {
+		return err
+	}
+	rowsAffect, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffect == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Purge removes the rows soft deleted before t for good and returns how many.
+// It removes them purgeBatchSize at a time, each batch in its own statement.
+func (repo *Repository[T]) Purge(ctx context.Context, before time.Time) (int64, error) {
+	if repo.Table.DeletedAt == "" {
+		return 0, repo.errNoSoftDelete()
+	}
+	deleted := []query.Cond{query.IsNotNull(repo.Table.DeletedAt), query.Lt(repo.Table.DeletedAt, before.UTC())}
+	b := query.Delete(repo.Table.Name)
+	if repo.dialect() == query.Postgres {
+		// Postgres has no DELETE ... LIMIT, a batch is the rows of a SELECT ... LIMIT,
+		// written with ? placeholders to go in Expr
+		batch, batchArgs, err := query.Select(`ctid`).
+			From(repo.Table.Name).
+			Where(deleted...).
+			Limit(purgeBatchSize).
+			ToSQL(query.MySQL)
+		if err != nil {
+			return 0, err
+		}
+		b.Where(query.Expr(`ctid IN (`+strings.TrimSpace(batch)+`)`, batchArgs...))
+	} else {
+		b.Where(deleted...).Limit(purgeBatchSize)
+	}
+	sqlstr, args, err := b.ToSQL(repo.dialect())
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for {
+		res, err := repo.ExecuteContext(ctx, sqlstr, args...)
+		if err != nil {
+			return total, err
+		}
+		rowsAffect, err := res.RowsAffected()
+		if err != nil {
+			return total, err
+		}
+		total += rowsAffect
+		if rowsAffect < purgeBatchSize {
+			return total, nil
+		}
+	}
+}
+
+func (repo *Repository[T]) errNoSoftDelete() error {
+	return fmt.Errorf("interfaces: table %s has no soft delete column", repo.Table.Name)
+}
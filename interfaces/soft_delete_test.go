@@ -0,0 +1,195 @@
+package interfaces
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// purgeMock is Mock whose deletes report the next of affected as RowsAffected
+type purgeMock struct {
+	Mock
+	affected *[]int64
+	queries  *[]string
+}
+
+type purgeResult struct {
+	Result
+	n int64
+}
+
+func (r purgeResult) RowsAffected() (int64, error) { return r.n, nil }
+
+func (m purgeMock) ExecuteContext(ctx context.Context, query string, args ...interface{}) (Result, error) {
+	*m.queries = append(*m.queries, query)
+	if m.errNo != 0 {
+		return m.Mock.ExecuteContext(ctx, query, args...)
+	}
+	n := (*m.affected)[0]
+	*m.affected = (*m.affected)[1:]
+	return purgeResult{n: n}, nil
+}
+
+func TestSQLRepository_SoftDelete(t *testing.T) {
+	stamp := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	defer func(f func() time.Time) { now = f }(now)
+	now = func() time.Time { return stamp }
+
+	r := newSQLRecorder(Mock{})
+	repo := SQLRepository{SQLhandler: r}
+	if err := repo.DeleteUser(context.Background(), 3); err != nil {
+		t.Fatalf("expected  %v, actual %v", nil, err)
+	}
+	if err := repo.RestoreUser(context.Background(), 3); err != nil {
+		t.Fatalf("expected  %v, actual %v", nil, err)
+	}
+	queries := []string{
+		"UPDATE users SET deleted_at = ?, updated_at = ? WHERE id = ? AND deleted_at IS NULL ",
+		"UPDATE users SET deleted_at = ?, updated_at = ? WHERE id = ? AND deleted_at IS NOT NULL ",
+	}
+	if !reflect.DeepEqual(*r.queries, queries) {
+		t.Errorf("expected  %v, actual %v", queries, *r.queries)
+	}
+	args := [][]interface{}{{stamp, stamp, uint(3)}, {nil, stamp, uint(3)}}
+	if !reflect.DeepEqual(*r.args, args) {
+		t.Errorf("expected  %v, actual %v", args, *r.args)
+	}
+}
+
+func TestSQLRepository_QueryOptions(t *testing.T) {
+	tests := []struct {
+		opts  []QueryOption
+		where string
+	}{
+		{opts: nil, where: "WHERE deleted_at IS NULL AND id = ? "},
+		{opts: []QueryOption{WithDeleted()}, where: "WHERE id = ? "},
+		{opts: []QueryOption{OnlyDeleted()}, where: "WHERE deleted_at IS NOT NULL AND id = ? "},
+		{opts: []QueryOption{OnlyDeleted(), WithDeleted()}, where: "WHERE id = ? "},
+	}
+	for i, test := range tests {
+		r := newSQLRecorder(Mock{})
+		repo := SQLRepository{SQLhandler: r}
+		if _, err := repo.FindUserByID(context.Background(), 1, test.opts...); err != nil {
+			t.Fatalf("%d, expected  %v, actual %v", i, nil, err)
+		}
+		query := "SELECT " + userColumns + " FROM users " + test.where
+		if (*r.queries)[0] != query {
+			t.Errorf("%d, expected  %v, actual %v", i, query, (*r.queries)[0])
+		}
+	}
+
+	// a cursor is bound to the scope it was issued for
+	Gi = 0
+	defer func() { Gi = 0 }()
+	repo := SQLRepository{SQLhandler: Mock{nextCnt: 1}, CursorKey: []byte("test key")}
+	page, err := repo.FindUsers(context.Background(), ListOptions{Limit: 1}, OnlyDeleted())
+	if err != nil || page.NextCursor == "" {
+		t.Fatalf("expected a next cursor, actual %+v %v", page, err)
+	}
+	if _, err := repo.FindUsers(context.Background(), ListOptions{Limit: 1, Cursor: page.NextCursor}); err != ErrInvalidCursor {
+		t.Errorf("expected  %v, actual %v", ErrInvalidCursor, err)
+	}
+	if _, err := repo.FindUsers(context.Background(), ListOptions{Limit: 1, Cursor: page.NextCursor}, OnlyDeleted()); err != nil {
+		t.Errorf("expected  %v, actual %v", nil, err)
+	}
+}
+
+func TestSQLRepository_RestoreUser(t *testing.T) {
+	tests := []struct {
+		m   SQLhandler
+		err error
+	}{
+		{
+			m:   Mock{errNo: 0},
+			err: nil,
+		},
+		{
+			m:   Mock{errNo: 5},
+			err: errors.New("error execute"),
+		},
+		{
+			m:   Mock{errNo: 7},
+			err: errors.New("error row affected"),
+		},
+		{
+			m:   Mock{errNo: 11},
+			err: ErrNotFound,
+		},
+	}
+	for i, test := range tests {
+		m := SQLRepository{SQLhandler: test.m}
+		err := m.RestoreUser(context.Background(), 1)
+		if test.err != nil {
+			if err == nil || err.Error() != test.err.Error() {
+				t.Errorf("%d, expected  %v, actual %v", i, test.err, err)
+			}
+		} else if err != nil {
+			t.Errorf("%d, expected  %v, actual %v", i, nil, err)
+		}
+	}
+}
+
+func TestSQLRepository_PurgeDeletedBefore(t *testing.T) {
+	before := time.Date(2020, 1, 1, 9, 0, 0, 0, time.FixedZone("JST", 9*60*60))
+	tests := []struct {
+		m        purgeMock
+		affected []int64
+		r        int64
+		err      error
+	}{
+		{affected: []int64{3}, r: 3},
+		{affected: []int64{purgeBatchSize, purgeBatchSize, 0}, r: 2 * purgeBatchSize},
+		{m: purgeMock{Mock: Mock{errNo: 5}}, err: errors.New("error execute")},
+	}
+	for i, test := range tests {
+		deletes := len(test.affected)
+		test.m.affected = &test.affected
+		test.m.queries = &[]string{}
+		repo := SQLRepository{SQLhandler: test.m}
+		r, err := repo.PurgeDeletedBefore(context.Background(), before)
+		if test.err != nil {
+			if err == nil || err.Error() != test.err.Error() {
+				t.Errorf("%d, expected  %v, actual %v", i, test.err, err)
+			}
+			continue
+		}
+		if err != nil || r != test.r {
+			t.Errorf("%d, expected  %v, actual %v %v", i, test.r, r, err)
+		}
+		if len(*test.m.queries) != deletes {
+			t.Errorf("%d, expected %d deletes, actual %d", i, deletes, len(*test.m.queries))
+		}
+	}
+
+	r := newSQLRecorder(Mock{})
+	if _, err := (&SQLRepository{SQLhandler: r}).PurgeDeletedBefore(context.Background(), before); err != nil {
+		t.Fatalf("expected  %v, actual %v", nil, err)
+	}
+	query := "DELETE FROM users WHERE deleted_at IS NOT NULL AND deleted_at < ? LIMIT ? "
+	if (*r.queries)[0] != query {
+		t.Errorf("expected  %v, actual %v", query, (*r.queries)[0])
+	}
+	args := []interface{}{before.UTC(), purgeBatchSize}
+	if !reflect.DeepEqual((*r.args)[0], args) {
+		t.Errorf("expected  %v, actual %v", args, (*r.args)[0])
+	}
+
+	// Postgres has no DELETE ... LIMIT
+	pg := newPgRecorder()
+	if _, err := (&SQLRepository{SQLhandler: pg}).PurgeDeletedBefore(context.Background(), before); err != nil {
+		t.Fatalf("expected  %v, actual %v", nil, err)
+	}
+	query = "DELETE FROM users WHERE ctid IN (SELECT ctid FROM users WHERE deleted_at IS NOT NULL AND deleted_at < $1 LIMIT $2) "
+	if (*pg.queries)[0] != query {
+		t.Errorf("expected  %v, actual %v", query, (*pg.queries)[0])
+	}
+	if !reflect.DeepEqual((*pg.args)[0], args) {
+		t.Errorf("expected  %v, actual %v", args, (*pg.args)[0])
+	}
+
+	if _, err := NewRepository[item](Mock{}, Table{Name: "items", PrimaryKey: "item_id"}).Purge(context.Background(), before); err == nil {
+		t.Errorf("expected error for a table without soft delete")
+	}
+}
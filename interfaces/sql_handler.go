@@ -1,10 +1,36 @@
 package interfaces
 
+import (
+	"context"
+	"database/sql"
+
+	"github.com/nakamura244/databasesql/interfaces/query"
+)
+
 type SQLhandler interface {
 	Execute(string, ...interface{}) (Result, error)
+	ExecuteContext(context.Context, string, ...interface{}) (Result, error)
 	Query(string, ...interface{}) (Rows, error)
+	QueryContext(context.Context, string, ...interface{}) (Rows, error)
 	QueryRow(string, ...interface{}) Row
+	QueryRowContext(context.Context, string, ...interface{}) Row
+	// ExecuteNamed and QueryNamed take :name or @name parameters bound from
+	// a map or a struct instead of ? placeholders
+	ExecuteNamed(context.Context, string, interface{}) (Result, error)
+	QueryNamed(context.Context, string, interface{}) (Rows, error)
 	Begin() (Tx, error)
+	BeginTx(context.Context, *sql.TxOptions) (Tx, error)
+	// WithTx runs the func in a transaction. It commits when the func returns nil
+	// and rolls back when it returns an error or panics.
+	WithTx(context.Context, *sql.TxOptions, func(Tx) error) error
+	Prepare(string) (Stmt, error)
+	PrepareContext(context.Context, string) (Stmt, error)
+}
+
+// Dialecter is a SQLhandler or Tx that tells the SQL dialect of its database.
+// Repository writes MySQL for a handler that is not one.
+type Dialecter interface {
+	Dialect() query.Dialect
 }
 
 type Result interface {
@@ -16,6 +42,11 @@ type Rows interface {
 	Scan(...interface{}) error
 	Next() bool
 	Close() error
+	// Err returns the error, if any, that ended the iteration early.
+	// Check it after Next returns false.
+	Err() error
+	Columns() ([]string, error)
+	ColumnTypes() ([]*sql.ColumnType, error)
 }
 
 type Row interface {
@@ -24,6 +55,33 @@ type Row interface {
 
 type Tx interface {
 	Execute(string, ...interface{}) (Result, error)
+	ExecuteContext(context.Context, string, ...interface{}) (Result, error)
+	Query(string, ...interface{}) (Rows, error)
+	QueryContext(context.Context, string, ...interface{}) (Rows, error)
+	QueryRow(string, ...interface{}) Row
+	QueryRowContext(context.Context, string, ...interface{}) Row
+	ExecuteNamed(context.Context, string, interface{}) (Result, error)
+	QueryNamed(context.Context, string, interface{}) (Rows, error)
+	Prepare(string) (Stmt, error)
+	PrepareContext(context.Context, string) (Stmt, error)
+	Stmt(Stmt) Stmt
+	StmtContext(context.Context, Stmt) Stmt
 	Commit() error
 	Rollback() error
+	Savepoint(string) error
+	RollbackTo(string) error
+	Release(string) error
+	// Begin and BeginTx start a nested transaction backed by a savepoint
+	Begin() (Tx, error)
+	BeginTx(context.Context, *sql.TxOptions) (Tx, error)
+}
+
+type Stmt interface {
+	Execute(...interface{}) (Result, error)
+	ExecuteContext(context.Context, ...interface{}) (Result, error)
+	Query(...interface{}) (Rows, error)
+	QueryContext(context.Context, ...interface{}) (Rows, error)
+	QueryRow(...interface{}) Row
+	QueryRowContext(context.Context, ...interface{}) Row
+	Close() error
 }
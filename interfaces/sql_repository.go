@@ -1,113 +1,283 @@
 package interfaces
 
 import (
-	"errors"
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nakamura244/databasesql/interfaces/query"
 )
 
 type SQLRepository struct {
 	SQLhandler
+	// CursorKey signs the page cursors of FindUsers. When it is empty a random
+	// key is made per process, so cursors do not survive a restart.
+	CursorKey []byte
+}
+
+// execer is what SQLhandler and Tx have in common
+type execer interface {
+	ExecuteContext(context.Context, string, ...interface{}) (Result, error)
+	QueryContext(context.Context, string, ...interface{}) (Rows, error)
+	QueryRowContext(context.Context, string, ...interface{}) Row
 }
 
 type User struct {
-	ID    uint   // id
-	Email string // email
+	ID        uint           `db:"id"`
+	Email     string         `db:"email"`
+	Name      sql.NullString `db:"name"`
+	Status    UserStatus     `db:"status"`
+	CreatedAt time.Time      `db:"created_at"` // UTC, set on insert
+	UpdatedAt time.Time      `db:"updated_at"` // UTC, set on insert and update
+	DeletedAt sql.NullTime   `db:"deleted_at"`
+	// Version is the optimistic lock, incremented by every update
+	Version uint `db:"version"`
 }
 
-func (repo *SQLRepository) FindUserByID(id uint) (*User, error) {
-	const sqlstr = `SELECT ` +
-		`id, email ` +
-		`FROM users ` +
-		`WHERE id = ? `
-	row, err := repo.Query(sqlstr, id)
-	if err != nil {
-		return nil, err
-	}
-	if !row.Next() {
-		return nil, errors.New("failed to row.Next()")
+// UserStatus is stored as TINYINT
+type UserStatus int8
+
+const (
+	UserStatusActive UserStatus = iota
+	UserStatusInactive
+	UserStatusSuspended
+)
+
+func (s UserStatus) String() string {
+	switch s {
+	case UserStatusActive:
+		return "active"
+	case UserStatusInactive:
+		return "inactive"
+	case UserStatusSuspended:
+		return "suspended"
+	default:
+		return "UserStatus(" + strconv.Itoa(int(s)) + ")"
 	}
-	u := &User{}
-	if err = row.Scan(&u.ID, &u.Email); err != nil {
-		return nil, err
+}
+
+const (
+	// DefaultListLimit is the page size when ListOptions.Limit is not set
+	DefaultListLimit = 20
+	// MaxListLimit is the largest page size
+	MaxListLimit = 1000
+)
+
+// SortOrder is the order of id
+type SortOrder int
+
+const (
+	Asc SortOrder = iota
+	Desc
+)
+
+// ListOptions is the filter and the page of FindUsers
+type ListOptions struct {
+	// Limit is the page size, DefaultListLimit when 0 and at most MaxListLimit
+	Limit int
+	// Offset skips rows. It is ignored when Cursor is set.
+	Offset int
+	// Cursor is UserPage.NextCursor of the previous page. The filter and the order
+	// must be the same as for that page.
+	Cursor      string
+	Email       string
+	EmailPrefix string
+	Order       SortOrder
+}
+
+// UserPage is a page of FindUsers
+type UserPage struct {
+	Users []*User
+	// NextCursor is empty on the last page
+	NextCursor string
+}
+
+// userTable is where User is stored
+var userTable = Table{
+	Name:       "users",
+	PrimaryKey: "id",
+	Columns:    []string{"id", "email", "name", "status", "created_at", "updated_at", "deleted_at", "version"},
+	CreatedAt:  "created_at",
+	UpdatedAt:  "updated_at",
+	DeletedAt:  "deleted_at",
+	Version:    "version",
+	UniqueKey:  []string{"email"},
+}
+
+// users is the generic repository SQLRepository is built on
+func (repo *SQLRepository) users() *Repository[User] {
+	return NewRepository[User](repo.SQLhandler, userTable)
+}
+
+// FindUserByID returns ErrNotFound when there is no such user.
+// Deleted users are not found unless opts say so.
+func (repo *SQLRepository) FindUserByID(ctx context.Context, id uint, opts ...QueryOption) (*User, error) {
+	return repo.users().FindByID(ctx, id, opts...)
+}
+
+// FindUserByIDForUpdate reads the user with id in tx and locks it with mode until tx ends.
+// It returns ErrNotFound when there is no such user, or when it is locked and mode has SkipLocked,
+// ErrLockNotAvailable when it is locked and mode has NoWait, and ErrLockTimeout when
+// the lock was not granted in time.
+func (repo *SQLRepository) FindUserByIDForUpdate(ctx context.Context, tx Tx, id uint, mode LockMode) (*User, error) {
+	return repo.users().FindByIDLocked(ctx, tx, id, mode)
+}
+
+// FindUsers is a page of users ordered by id. It returns ErrInvalidCursor
+// when opts.Cursor was altered or issued for another filter or order.
+// Deleted users are left out unless qopts say so.
+func (repo *SQLRepository) FindUsers(ctx context.Context, opts ListOptions, qopts ...QueryOption) (*UserPage, error) {
+	scope := newQueryOptions(qopts).deleted
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultListLimit
 	}
-	err = row.Close()
-	if err != nil {
-		return nil, err
+	if limit > MaxListLimit {
+		limit = MaxListLimit
 	}
-	return u, nil
-}
 
-func (repo *SQLRepository) FindUsers() ([]*User, error) {
-	const sqlstr = `SELECT ` +
-		`id, email ` +
-		`FROM users `
-	q, err := repo.Query(sqlstr)
-	if err != nil {
-		return nil, err
+	where := []query.Cond{}
+	if opts.Email != "" {
+		where = append(where, query.Eq(`email`, opts.Email))
 	}
-	res := []*User{}
-	for q.Next() {
-		u := User{}
-		err = q.Scan(&u.ID, &u.Email)
+	if opts.EmailPrefix != "" {
+		where = append(where, query.Like(`email`, escapeLike(opts.EmailPrefix)+"%"))
+	}
+	if opts.Cursor != "" {
+		c, err := decodeCursor(repo.cursorKey(), opts.Cursor)
 		if err != nil {
 			return nil, err
 		}
-		res = append(res, &u)
+		if c.Order != opts.Order || c.Email != opts.Email || c.EmailPrefix != opts.EmailPrefix || c.Deleted != scope {
+			return nil, ErrInvalidCursor
+		}
+		if opts.Order == Desc {
+			where = append(where, query.Lt(`id`, c.LastID))
+		} else {
+			where = append(where, query.Gt(`id`, c.LastID))
+		}
+	}
+
+	c := Criteria{
+		Where:   where,
+		OrderBy: `id ASC`,
+		// one more row tells whether there is a next page
+		Limit: limit + 1,
+	}
+	if opts.Order == Desc {
+		c.OrderBy = `id DESC`
+	}
+	if opts.Cursor == "" {
+		c.Offset = opts.Offset
 	}
-	err = q.Close()
+
+	users, err := repo.users().Find(ctx, c, qopts...)
 	if err != nil {
 		return nil, err
 	}
-	return res, nil
+	page := &UserPage{Users: users}
+	if len(users) > limit {
+		page.Users = users[:limit]
+		page.NextCursor = encodeCursor(repo.cursorKey(), cursor{
+			LastID:      page.Users[limit-1].ID,
+			Order:       opts.Order,
+			Email:       opts.Email,
+			EmailPrefix: opts.EmailPrefix,
+			Deleted:     scope,
+		})
+	}
+	return page, nil
+}
+
+// escapeLike escapes the wildcards of LIKE with the default escape character
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// UserIterator streams every user ordered by id. The caller must Close it
+// unless it iterates to the end.
+func (repo *SQLRepository) UserIterator(ctx context.Context, opts ...QueryOption) (*Iterator[User], error) {
+	return repo.users().Iterate(ctx, Criteria{OrderBy: `id ASC`}, opts...)
 }
 
-func (repo *SQLRepository) InsertUser(u *User) (uint, error) {
-	const sql = `INSERT INTO users ( ` +
-		`email ` +
-		`) VALUES (?) `
-	res, err := repo.Execute(sql, u.Email)
+// EachUser calls fn for every user ordered by id, one row in memory at a time.
+// It stops at the first error of fn or when ctx is done and returns that error.
+func (repo *SQLRepository) EachUser(ctx context.Context, fn func(*User) error, opts ...QueryOption) error {
+	it, err := repo.UserIterator(ctx, opts...)
 	if err != nil {
-		return 0, err
+		return err
 	}
-	id, err := res.LastInsertId()
+	return it.Each(fn)
+}
+
+func (repo *SQLRepository) InsertUser(ctx context.Context, u *User) (uint, error) {
+	id, err := repo.users().Insert(ctx, u)
 	if err != nil {
 		return 0, err
 	}
 	return uint(id), nil
 }
 
-func (repo *SQLRepository) InsertUserWithTx(u *User) (uint, error) {
-	tx, err := repo.Begin()
+func (repo *SQLRepository) InsertUserWithTx(ctx context.Context, u *User) (uint, error) {
+	id, err := repo.users().InsertWithTx(ctx, u)
 	if err != nil {
 		return 0, err
 	}
+	return uint(id), nil
+}
 
-	const sql = `INSERT INTO users ( ` +
-		`email ` +
-		`) VALUES (?) `
-	res, err := tx.Execute(sql, u.Email)
+// InsertUsers inserts us in one transaction and returns their ids in the order of us
+func (repo *SQLRepository) InsertUsers(ctx context.Context, us []*User) ([]uint, error) {
+	ids, err := repo.users().InsertMany(ctx, us)
 	if err != nil {
-		tx.Rollback()
-		return 0, err
+		return nil, err
 	}
-	lastID, err := res.LastInsertId()
-	if err != nil {
-		tx.Rollback()
-		return 0, err
+	res := make([]uint, len(ids))
+	for i, id := range ids {
+		res[i] = uint(id)
 	}
+	return res, nil
+}
 
-	rowsAffect, err := res.RowsAffected()
+// UpsertUserByEmail inserts u, or updates the user with the same email.
+// It returns the id of the user and whether it was inserted.
+func (repo *SQLRepository) UpsertUserByEmail(ctx context.Context, u *User) (uint, bool, error) {
+	id, inserted, err := repo.users().Upsert(ctx, u)
 	if err != nil {
-		tx.Rollback()
-		return 0, err
-	}
-	if rowsAffect != 1 {
-		tx.Rollback()
-		return 0, errors.New("rowsAffect != 0 error")
+		return 0, false, err
 	}
+	return uint(id), inserted, nil
+}
 
-	err = tx.Commit()
-	if err != nil {
-		return 0, err
-	}
-	return uint(lastID), nil
-}
\ No newline at end of file
+// UpdateUser updates the user with u.ID if it still has u.Version, and increments u.Version.
+// It returns ErrNotFound when there is no such user and ErrStaleObject when
+// the user was updated since u was read.
+func (repo *SQLRepository) UpdateUser(ctx context.Context, u *User) error {
+	return repo.users().Update(ctx, u)
+}
+
+func (repo *SQLRepository) UpdateUserWithTx(ctx context.Context, u *User) error {
+	return repo.users().UpdateWithTx(ctx, u)
+}
+
+// DeleteUser soft deletes the user with id by setting DeletedAt.
+// It returns ErrNotFound when there is no such user or it is deleted already.
+func (repo *SQLRepository) DeleteUser(ctx context.Context, id uint) error {
+	return repo.users().Delete(ctx, id)
+}
+
+func (repo *SQLRepository) DeleteUserWithTx(ctx context.Context, id uint) error {
+	return repo.users().DeleteWithTx(ctx, id)
+}
+
+// RestoreUser undoes DeleteUser. It returns ErrNotFound when there is no deleted user with id.
+func (repo *SQLRepository) RestoreUser(ctx context.Context, id uint) error {
+	return repo.users().Restore(ctx, id)
+}
+
+// PurgeDeletedBefore removes the users deleted before t for good and returns how many
+func (repo *SQLRepository) PurgeDeletedBefore(ctx context.Context, t time.Time) (int64, error) {
+	return repo.users().Purge(ctx, t)
+}
@@ -1,6 +1,7 @@
 package interfaces
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"github.com/go-sql-driver/mysql"
@@ -23,13 +24,34 @@ type Mock struct {
 	// 9 -> Commit error
 	// 10 -> Rollback error
 	// 11 -> RowsAffected = 0 error
+	// 12 -> Rows.Err error
+	// 13 -> RowsAffected = 2
 	errNo   int
 	nextCnt int
 	err     error
+	closed  *int
 }
 
 var Gi int
 
+// userColumns is the columns of userTable in a statement
+const userColumns = "id, email, name, status, created_at, updated_at, deleted_at, version"
+
+// mockUser is the User scanned from Mock
+func mockUser() *User {
+	t, _ := time.Parse("2006-01-02", "2014-12-31")
+	return &User{
+		ID:        2,
+		Email:     "test string",
+		Name:      sql.NullString{String: "test string", Valid: true},
+		Status:    UserStatusInactive,
+		CreatedAt: t,
+		UpdatedAt: t,
+		DeletedAt: sql.NullTime{Time: t, Valid: true},
+		Version:   2,
+	}
+}
+
 
 func (m Mock) Query(string, ...interface{}) (Rows, error) {
 	if m.errNo == 1 {
@@ -39,6 +61,18 @@ func (m Mock) Query(string, ...interface{}) (Rows, error) {
 }
 
 
+func (m Mock) QueryContext(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+	return m.Query(query, args...)
+}
+
+func (m Mock) QueryRow(string, ...interface{}) Row {
+	return &m
+}
+
+func (m Mock) QueryRowContext(ctx context.Context, query string, args ...interface{}) Row {
+	return m.QueryRow(query, args...)
+}
+
 func (m Mock) Scan(dest ...interface{}) error {
 	if m.err != nil {
 		return m.err
@@ -54,6 +88,8 @@ func (m Mock) Scan(dest ...interface{}) error {
 			*s = 2
 		case *uint:
 			*s = 2
+		case *int64:
+			*s = 2
 		case *sql.NullString:
 			*s = sql.NullString{String: "test string", Valid: true}
 		case *sql.NullInt64:
@@ -67,6 +103,13 @@ func (m Mock) Scan(dest ...interface{}) error {
 		case *mysql.NullTime:
 			t, _ := time.Parse("2006-01-02", "2014-12-31")
 			*s = mysql.NullTime{Time: t, Valid: true}
+		case *sql.NullTime:
+			t, _ := time.Parse("2006-01-02", "2014-12-31")
+			*s = sql.NullTime{Time: t, Valid: true}
+		case *time.Time:
+			*s, _ = time.Parse("2006-01-02", "2014-12-31")
+		case *UserStatus:
+			*s = UserStatusInactive
 		}
 	}
 	return nil
@@ -74,6 +117,9 @@ func (m Mock) Scan(dest ...interface{}) error {
 
 
 func (m Mock) Close() error {
+	if m.closed != nil {
+		*m.closed++
+	}
 	if m.errNo == 3 {
 		return errors.New("error close")
 	}
@@ -81,7 +127,7 @@ func (m Mock) Close() error {
 }
 
 func (m Mock) Next() bool {
-	if m.errNo == 4 {
+	if m.errNo == 4 || m.errNo == 12 {
 		return false
 	}
 	if m.nextCnt == 1 {
@@ -94,6 +140,21 @@ func (m Mock) Next() bool {
 }
 
 
+func (m Mock) Err() error {
+	if m.errNo == 12 {
+		return errors.New("error rows")
+	}
+	return nil
+}
+
+func (m Mock) Columns() ([]string, error) {
+	return userTable.Columns, nil
+}
+
+func (m Mock) ColumnTypes() ([]*sql.ColumnType, error) {
+	return nil, nil
+}
+
 func (m Mock) Execute(string, ...interface{}) (Result, error) {
 	if m.errNo == 5 {
 		return &Mock{}, errors.New("error execute")
@@ -101,6 +162,18 @@ func (m Mock) Execute(string, ...interface{}) (Result, error) {
 	return &m, nil
 }
 
+func (m Mock) ExecuteContext(ctx context.Context, query string, args ...interface{}) (Result, error) {
+	return m.Execute(query, args...)
+}
+
+func (m Mock) ExecuteNamed(ctx context.Context, query string, arg interface{}) (Result, error) {
+	return m.Execute(query)
+}
+
+func (m Mock) QueryNamed(ctx context.Context, query string, arg interface{}) (Rows, error) {
+	return m.Query(query)
+}
+
 func (m Mock) LastInsertId() (int64, error) {
 	if m.errNo == 6 {
 		return int64(0), errors.New("error last insert id")
@@ -115,6 +188,9 @@ func (m Mock) RowsAffected() (int64, error) {
 	if m.errNo == 11 {
 		return int64(0), nil
 	}
+	if m.errNo == 13 {
+		return int64(2), nil
+	}
 	return int64(1), nil
 }
 
@@ -125,6 +201,44 @@ func (m Mock) Begin() (Tx, error) {
 	return &m, nil
 }
 
+func (m Mock) BeginTx(ctx context.Context, opts *sql.TxOptions) (Tx, error) {
+	return m.Begin()
+}
+
+func (m Mock) WithTx(ctx context.Context, opts *sql.TxOptions, fn func(Tx) error) error {
+	tx, err := m.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return errors.Join(err, rbErr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+func (m Mock) Stmt(s Stmt) Stmt {
+	return s
+}
+
+func (m Mock) StmtContext(ctx context.Context, s Stmt) Stmt {
+	return s
+}
+
+func (m Mock) Savepoint(string) error {
+	return nil
+}
+
+func (m Mock) RollbackTo(string) error {
+	return nil
+}
+
+func (m Mock) Release(string) error {
+	return nil
+}
+
 func (m Mock) Commit() error {
 	if m.errNo == 9 {
 		return errors.New("error commit")
@@ -154,7 +268,7 @@ func TestSQLRepository_FindUserByID(t *testing.T) {
 		},
 		{
 			m:   Mock{errNo: 4},
-			err: errors.New("failed to row.Next()"),
+			err: ErrNotFound,
 		},
 		{
 			m:   Mock{errNo: 2},
@@ -164,20 +278,21 @@ func TestSQLRepository_FindUserByID(t *testing.T) {
 			m:   Mock{errNo: 3},
 			err: errors.New("error close"),
 		},
+		{
+			m:   Mock{errNo: 12},
+			err: errors.New("error rows"),
+		},
 	}
 
 	for i, test := range tests {
-		m := SQLRepository{test.m}
-		r, err := m.FindUserByID(1)
+		m := SQLRepository{SQLhandler: test.m}
+		r, err := m.FindUserByID(context.Background(), 1)
 		if test.err != nil {
 			if err.Error() != test.err.Error() {
 				t.Errorf("%d, expected  %v, actual %v", i, test.err, err)
 			}
 		} else {
-			expected := &User{
-				ID:2,
-				Email:"test string",
-			}
+			expected := mockUser()
 			if reflect.DeepEqual(r, expected) == false {
 				t.Errorf("expected %+v, got %+v", expected, r)
 			}
@@ -206,27 +321,162 @@ func TestSQLRepository_FindUsers(t *testing.T) {
 			m:   Mock{nextCnt: 1, errNo: 3},
 			err: errors.New("error close"),
 		},
+		{
+			m:   Mock{errNo: 12},
+			err: errors.New("error rows"),
+		},
 	}
 	for i, test := range tests {
 		Gi = 0
-		m := SQLRepository{test.m}
-		r, err := m.FindUsers()
+		m := SQLRepository{SQLhandler: test.m}
+		r, err := m.FindUsers(context.Background(), ListOptions{})
 		if test.err != nil {
 			if err.Error() != test.err.Error() {
 				t.Errorf("%d, expected  %v, actual %v", i, test.err, err)
 			}
 		} else {
-			expected := &User{
-				ID:2,
-				Email:"test string",
+			expected := mockUser()
+			if reflect.DeepEqual(r.Users[0], expected) == false {
+				t.Errorf("expected %+v, got %+v", expected, r.Users[0])
 			}
-			if reflect.DeepEqual(r[0], expected) == false {
-				t.Errorf("expected %+v, got %+v", expected, r[0])
+			if r.NextCursor != "" {
+				t.Errorf("%d, expected no next cursor, actual %v", i, r.NextCursor)
 			}
 		}
 	}
 }
 
+func TestSQLRepository_FindUsersQuery(t *testing.T) {
+	tests := []struct{
+		opts  ListOptions
+		query string
+		args  []interface{}
+	} {
+		{
+			opts:  ListOptions{},
+			query: "SELECT " + userColumns + " FROM users WHERE deleted_at IS NULL ORDER BY id ASC LIMIT ? ",
+			args:  []interface{}{DefaultListLimit + 1},
+		},
+		{
+			opts:  ListOptions{Limit: 5, Offset: 10, Order: Desc},
+			query: "SELECT " + userColumns + " FROM users WHERE deleted_at IS NULL ORDER BY id DESC LIMIT ? OFFSET ? ",
+			args:  []interface{}{6, 10},
+		},
+		{
+			opts:  ListOptions{Limit: MaxListLimit + 1, Email: "a@example.com", EmailPrefix: `a_1%\`},
+			query: "SELECT " + userColumns + " FROM users WHERE deleted_at IS NULL AND email = ? AND email LIKE ? ORDER BY id ASC LIMIT ? ",
+			args:  []interface{}{"a@example.com", `a\_1\%\\%`, MaxListLimit + 1},
+		},
+	}
+	for i, test := range tests {
+		Gi = 0
+		r := newSQLRecorder(Mock{nextCnt: 1})
+		m := SQLRepository{SQLhandler: r}
+		if _, err := m.FindUsers(context.Background(), test.opts); err != nil {
+			t.Fatalf("%d, expected  %v, actual %v", i, nil, err)
+		}
+		if (*r.queries)[0] != test.query {
+			t.Errorf("%d, expected  %v, actual %v", i, test.query, (*r.queries)[0])
+		}
+		if !reflect.DeepEqual((*r.args)[0], test.args) {
+			t.Errorf("%d, expected  %v, actual %v", i, test.args, (*r.args)[0])
+		}
+	}
+}
+
+func TestSQLRepository_FindUsersCursor(t *testing.T) {
+	Gi = 0
+	r := newSQLRecorder(Mock{nextCnt: 1})
+	m := SQLRepository{SQLhandler: r, CursorKey: []byte("test key")}
+	opts := ListOptions{Limit: 1, EmailPrefix: "a", Order: Desc}
+	page, err := m.FindUsers(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("expected  %v, actual %v", nil, err)
+	}
+	if len(page.Users) != 1 || page.NextCursor == "" {
+		t.Fatalf("expected 1 user and a next cursor, actual %+v", page)
+	}
+
+	Gi = 0
+	opts.Cursor = page.NextCursor
+	opts.Offset = 3
+	if _, err := m.FindUsers(context.Background(), opts); err != nil {
+		t.Fatalf("expected  %v, actual %v", nil, err)
+	}
+	query := "SELECT " + userColumns + " FROM users WHERE deleted_at IS NULL AND email LIKE ? AND id < ? ORDER BY id DESC LIMIT ? "
+	if (*r.queries)[1] != query {
+		t.Errorf("expected  %v, actual %v", query, (*r.queries)[1])
+	}
+	args := []interface{}{"a%", uint(2), 2}
+	if !reflect.DeepEqual((*r.args)[1], args) {
+		t.Errorf("expected  %v, actual %v", args, (*r.args)[1])
+	}
+
+	invalid := []ListOptions{
+		{Limit: 1, EmailPrefix: "b", Order: Desc, Cursor: page.NextCursor},
+		{Limit: 1, EmailPrefix: "a", Order: Asc, Cursor: page.NextCursor},
+		{Cursor: page.NextCursor + "x"},
+		{Cursor: "x" + page.NextCursor},
+		{Cursor: "not a cursor"},
+	}
+	for i, opts := range invalid {
+		if _, err := m.FindUsers(context.Background(), opts); err != ErrInvalidCursor {
+			t.Errorf("%d, expected  %v, actual %v", i, ErrInvalidCursor, err)
+		}
+	}
+	other := SQLRepository{SQLhandler: r, CursorKey: []byte("other key")}
+	if _, err := other.FindUsers(context.Background(), ListOptions{Limit: 1, EmailPrefix: "a", Order: Desc, Cursor: page.NextCursor}); err != ErrInvalidCursor {
+		t.Errorf("expected  %v, actual %v", ErrInvalidCursor, err)
+	}
+}
+
+func TestSQLRepository_ReadersCloseRows(t *testing.T) {
+	tests := []struct{
+		m   Mock
+		err error
+	} {
+		{
+			m:   Mock{errNo: 0},
+			err: nil,
+		},
+		{
+			m:   Mock{errNo: 2},
+			err: errors.New("error scan"),
+		},
+		{
+			m:   Mock{errNo: 4},
+			err: ErrNotFound,
+		},
+		{
+			m:   Mock{errNo: 12},
+			err: errors.New("error rows"),
+		},
+	}
+	for i, test := range tests {
+		Gi = 0
+		test.m.nextCnt = 1
+		test.m.closed = new(int)
+		m := SQLRepository{SQLhandler: test.m}
+		_, err := m.FindUserByID(context.Background(), 1)
+		if (err == nil) != (test.err == nil) || err != nil && err.Error() != test.err.Error() {
+			t.Errorf("%d, expected  %v, actual %v", i, test.err, err)
+		}
+		if *test.m.closed != 1 {
+			t.Errorf("%d, FindUserByID expected rows closed once, actual %d", i, *test.m.closed)
+		}
+
+		Gi = 0
+		*test.m.closed = 0
+		_, err = m.FindUsers(context.Background(), ListOptions{})
+		if test.m.errNo != 4 && ((err == nil) != (test.err == nil) || err != nil && err.Error() != test.err.Error()) {
+			t.Errorf("%d, expected  %v, actual %v", i, test.err, err)
+		}
+		if *test.m.closed != 1 {
+			t.Errorf("%d, FindUsers expected rows closed once, actual %d", i, *test.m.closed)
+		}
+	}
+}
+
 func TestSQLRepository_InsertUser(t *testing.T) {
 	tests := []struct{
 		m SQLhandler
@@ -248,9 +498,9 @@ func TestSQLRepository_InsertUser(t *testing.T) {
 	for i, test := range tests {
 		Gi = 0
 		m := SQLRepository{
-			test.m,
+			SQLhandler: test.m,
 		}
-		r, err := m.InsertUser(&User{})
+		r, err := m.InsertUser(context.Background(), &User{})
 		if test.err != nil {
 			if err.Error() != test.err.Error() {
 				t.Errorf("%d, expected  %v, actual %v", i, test.err, err)
@@ -291,21 +541,25 @@ func TestSQLRepository_InsertUserWithTx(t *testing.T) {
 		},
 		{
 			m:   Mock{errNo: 11},
-			err: errors.New("rowsAffect != 0 error"),
+			err: ErrUnexpectedRowCount,
 		},
 		{
 			m:   Mock{errNo: 9},
 			err: errors.New("error commit"),
 		},
+		{
+			m:   Mock{errNo: 10},
+			err: nil,
+		},
 	}
 	for i, test := range tests {
 		Gi = 0
 		m := SQLRepository{
-			test.m,
+			SQLhandler: test.m,
 		}
-		r, err := m.InsertUserWithTx(&User{})
+		r, err := m.InsertUserWithTx(context.Background(), &User{})
 		if test.err != nil {
-			if err.Error() != test.err.Error() {
+			if !errors.Is(err, test.err) && err.Error() != test.err.Error() {
 				t.Errorf("%d, expected  %v, actual %v", i, test.err, err)
 			}
 		} else {
@@ -315,4 +569,270 @@ func TestSQLRepository_InsertUserWithTx(t *testing.T) {
 			}
 		}
 	}
-}
\ No newline at end of file
+}
+
+func TestSQLRepository_UpsertUserByEmail(t *testing.T) {
+	tests := []struct{
+		m        SQLhandler
+		r        uint
+		inserted bool
+		err      error
+	} {
+		{
+			m:        Mock{errNo: 0},
+			r:        1,
+			inserted: true,
+			err:      nil,
+		},
+		{
+			m:        Mock{errNo: 13},
+			r:        1,
+			inserted: false,
+			err:      nil,
+		},
+		{
+			m:        Mock{errNo: 11},
+			r:        1,
+			inserted: false,
+			err:      nil,
+		},
+		{
+			m:   Mock{errNo: 5},
+			err: errors.New("error execute"),
+		},
+		{
+			m:   Mock{errNo: 6},
+			err: errors.New("error last insert id"),
+		},
+		{
+			m:   Mock{errNo: 7},
+			err: errors.New("error row affected"),
+		},
+	}
+	for i, test := range tests {
+		m := SQLRepository{SQLhandler: test.m}
+		r, inserted, err := m.UpsertUserByEmail(context.Background(), &User{Email: "email@example.com"})
+		if test.err != nil {
+			if err.Error() != test.err.Error() {
+				t.Errorf("%d, expected  %v, actual %v", i, test.err, err)
+			}
+		} else {
+			if err != nil {
+				t.Errorf("%d, expected  %v, actual %v", i, nil, err)
+			}
+			if r != test.r || inserted != test.inserted {
+				t.Errorf("%d, expected %v %v, got %v %v", i, test.r, test.inserted, r, inserted)
+			}
+		}
+	}
+
+	r := newSQLRecorder(Mock{})
+	m := SQLRepository{SQLhandler: r}
+	if _, _, err := m.UpsertUserByEmail(context.Background(), &User{Email: "email@example.com"}); err != nil {
+		t.Fatalf("expected  %v, actual %v", nil, err)
+	}
+	query := "INSERT INTO users ( email, name, status, created_at, updated_at, deleted_at, version ) VALUES (?, ?, ?, ?, ?, ?, ?) " +
+		"ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id), email = VALUES(email), name = VALUES(name), " +
+		"status = VALUES(status), updated_at = VALUES(updated_at), deleted_at = NULL, version = version + 1 "
+	if (*r.queries)[0] != query {
+		t.Errorf("expected  %v, actual %v", query, (*r.queries)[0])
+	}
+}
+
+func TestSQLRepository_UpdateUser(t *testing.T) {
+	tests := []struct {
+		m   SQLhandler
+		err error
+	}{
+		{
+			m:   Mock{errNo: 0},
+			err: nil,
+		},
+		{
+			m:   Mock{errNo: 5},
+			err: errors.New("error execute"),
+		},
+		{
+			m:   Mock{errNo: 7},
+			err: errors.New("error row affected"),
+		},
+		{
+			// the user is there with another version
+			m:   Mock{errNo: 11},
+			err: ErrStaleObject,
+		},
+		{
+			m:   Mock{errNo: 11, err: &DBError{Kind: ErrNotFound, Err: sql.ErrNoRows}},
+			err: ErrNotFound,
+		},
+	}
+	for i, test := range tests {
+		m := SQLRepository{SQLhandler: test.m}
+		u := &User{ID: 1, Email: "test string", Version: 3}
+		err := m.UpdateUser(context.Background(), u)
+		if test.err != nil {
+			if err == nil || err.Error() != test.err.Error() {
+				t.Errorf("%d, expected  %v, actual %v", i, test.err, err)
+			}
+			if u.Version != 3 {
+				t.Errorf("%d, expected version to stay %v, actual %v", i, 3, u.Version)
+			}
+		} else {
+			if err != nil {
+				t.Errorf("%d, expected  %v, actual %v", i, nil, err)
+			}
+			if u.Version != 4 {
+				t.Errorf("%d, expected version %v, actual %v", i, 4, u.Version)
+			}
+		}
+	}
+}
+
+func TestSQLRepository_UpdateUserWithTx(t *testing.T) {
+	tests := []struct {
+		m   SQLhandler
+		err error
+	}{
+		{
+			m:   Mock{errNo: 0},
+			err: nil,
+		},
+		{
+			m:   Mock{errNo: 8},
+			err: errors.New("error begin"),
+		},
+		{
+			m:   Mock{errNo: 5},
+			err: errors.New("error execute"),
+		},
+		{
+			m:   Mock{errNo: 11, err: &DBError{Kind: ErrNotFound, Err: sql.ErrNoRows}},
+			err: ErrNotFound,
+		},
+		{
+			m:   Mock{errNo: 9},
+			err: errors.New("error commit"),
+		},
+	}
+	for i, test := range tests {
+		m := SQLRepository{SQLhandler: test.m}
+		u := &User{ID: 1, Email: "test string", Version: 3}
+		err := m.UpdateUserWithTx(context.Background(), u)
+		if test.err != nil {
+			if err == nil || err.Error() != test.err.Error() {
+				t.Errorf("%d, expected  %v, actual %v", i, test.err, err)
+			}
+			if u.Version != 3 {
+				t.Errorf("%d, expected version to stay %v, actual %v", i, 3, u.Version)
+			}
+		} else {
+			if err != nil {
+				t.Errorf("%d, expected  %v, actual %v", i, nil, err)
+			}
+			if u.Version != 4 {
+				t.Errorf("%d, expected version %v, actual %v", i, 4, u.Version)
+			}
+		}
+	}
+
+	// a retried transaction updates with the version read, and bumps it once
+	r := retryMock{newSQLRecorder(Mock{errNo: 0})}
+	m := SQLRepository{SQLhandler: r}
+	u := &User{ID: 1, Email: "test string", Version: 3}
+	if err := m.UpdateUserWithTx(context.Background(), u); err != nil {
+		t.Fatalf("expected  %v, actual %v", nil, err)
+	}
+	if u.Version != 4 {
+		t.Errorf("expected version %v, actual %v", 4, u.Version)
+	}
+	if len(*r.args) != 2 {
+		t.Errorf("expected 2 updates, actual %d", len(*r.args))
+	}
+	for i, args := range *r.args {
+		if v := args[len(args)-1]; v != uint(3) {
+			t.Errorf("%d, expected version %v in where, actual %v", i, 3, v)
+		}
+	}
+}
+
+// retryMock is sqlRecorder that runs the function of WithTx twice like a retried transaction
+type retryMock struct {
+	sqlRecorder
+}
+
+func (m retryMock) WithTx(ctx context.Context, opts *sql.TxOptions, fn func(Tx) error) error {
+	if err := fn(m.sqlRecorder); err != nil {
+		return err
+	}
+	return fn(m.sqlRecorder)
+}
+
+func TestSQLRepository_DeleteUser(t *testing.T) {
+	tests := []struct {
+		m   SQLhandler
+		err error
+	}{
+		{
+			m:   Mock{errNo: 0},
+			err: nil,
+		},
+		{
+			m:   Mock{errNo: 5},
+			err: errors.New("error execute"),
+		},
+		{
+			m:   Mock{errNo: 7},
+			err: errors.New("error row affected"),
+		},
+		{
+			m:   Mock{errNo: 11},
+			err: ErrNotFound,
+		},
+	}
+	for i, test := range tests {
+		m := SQLRepository{SQLhandler: test.m}
+		err := m.DeleteUser(context.Background(), 1)
+		if test.err != nil {
+			if err == nil || err.Error() != test.err.Error() {
+				t.Errorf("%d, expected  %v, actual %v", i, test.err, err)
+			}
+		} else if err != nil {
+			t.Errorf("%d, expected  %v, actual %v", i, nil, err)
+		}
+	}
+}
+
+func TestSQLRepository_DeleteUserWithTx(t *testing.T) {
+	tests := []struct {
+		m   SQLhandler
+		err error
+	}{
+		{
+			m:   Mock{errNo: 0},
+			err: nil,
+		},
+		{
+			m:   Mock{errNo: 8},
+			err: errors.New("error begin"),
+		},
+		{
+			m:   Mock{errNo: 11},
+			err: ErrNotFound,
+		},
+		{
+			m:   Mock{errNo: 9},
+			err: errors.New("error commit"),
+		},
+	}
+	for i, test := range tests {
+		m := SQLRepository{SQLhandler: test.m}
+		err := m.DeleteUserWithTx(context.Background(), 1)
+		if test.err != nil {
+			if err == nil || err.Error() != test.err.Error() {
+				t.Errorf("%d, expected  %v, actual %v", i, test.err, err)
+			}
+		} else if err != nil {
+			t.Errorf("%d, expected  %v, actual %v", i, nil, err)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package interfaces
+
+import "context"
+
+type txKey struct{}
+
+// ContextWithTx is ctx carrying tx. SQLhandler.BeginTx and WithTx called with
+// it begin a nested transaction in tx, so transactional repository methods
+// can be composed in one transaction.
+func ContextWithTx(ctx context.Context, tx Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// TxFromContext is the transaction carried by ctx
+func TxFromContext(ctx context.Context) (Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(Tx)
+	return tx, ok
+}
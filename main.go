@@ -1,16 +1,33 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"github.com/labstack/gommon/log"
 	"github.com/nakamura244/databasesql/db"
 	"github.com/nakamura244/databasesql/interfaces"
 	"github.com/nakamura244/databasesql/repository"
+	"time"
 )
 
 
 func main() {
-	conn := db.NewConn()
+	cfg, err := db.ConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	conn, err := db.Open(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := conn.Shutdown(ctx); err != nil {
+			log.Error(err)
+		}
+	}()
+	ctx := context.Background()
 
 	var u *interfaces.User
 	var sqlRepo repository.DBRepository
@@ -20,7 +37,7 @@ func main() {
 
 	// insert
 	u = &interfaces.User{Email:"email@example.com"}
-	id, err := sqlRepo.InsertUser(u)
+	id, err := sqlRepo.InsertUser(ctx, u)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -28,7 +45,7 @@ func main() {
 
 	// insert with tx
 	u = &interfaces.User{Email:"email@example.com"}
-	id, err = sqlRepo.InsertUserWithTx(u)
+	id, err = sqlRepo.InsertUserWithTx(ctx, u)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -36,7 +53,7 @@ func main() {
 
 
 	// select
-	user, err := sqlRepo.FindUserByID(id)
+	user, err := sqlRepo.FindUserByID(ctx, id)
 	if err != nil {
 		log.Fatal(err)
 	}
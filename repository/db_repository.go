@@ -1,12 +1,25 @@
 package repository
 
 import (
+	"context"
+	"time"
+
 	"github.com/nakamura244/databasesql/interfaces"
 )
 
 type DBRepository interface {
-	FindUserByID(id uint) (*interfaces.User, error)
-	FindUsers() ([]*interfaces.User, error)
-	InsertUser(u *interfaces.User) (uint, error)
-	InsertUserWithTx(u *interfaces.User) (uint, error)
+	FindUserByID(ctx context.Context, id uint, opts ...interfaces.QueryOption) (*interfaces.User, error)
+	FindUserByIDForUpdate(ctx context.Context, tx interfaces.Tx, id uint, mode interfaces.LockMode) (*interfaces.User, error)
+	FindUsers(ctx context.Context, opts interfaces.ListOptions, qopts ...interfaces.QueryOption) (*interfaces.UserPage, error)
+	EachUser(ctx context.Context, fn func(*interfaces.User) error, opts ...interfaces.QueryOption) error
+	InsertUser(ctx context.Context, u *interfaces.User) (uint, error)
+	InsertUserWithTx(ctx context.Context, u *interfaces.User) (uint, error)
+	InsertUsers(ctx context.Context, us []*interfaces.User) ([]uint, error)
+	UpsertUserByEmail(ctx context.Context, u *interfaces.User) (uint, bool, error)
+	UpdateUser(ctx context.Context, u *interfaces.User) error
+	UpdateUserWithTx(ctx context.Context, u *interfaces.User) error
+	DeleteUser(ctx context.Context, id uint) error
+	DeleteUserWithTx(ctx context.Context, id uint) error
+	RestoreUser(ctx context.Context, id uint) error
+	PurgeDeletedBefore(ctx context.Context, t time.Time) (int64, error)
 }
\ No newline at end of file